@@ -6,8 +6,10 @@ import (
 	_ "embed"
 	"encoding/base64"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"mdview/converter"
@@ -20,6 +22,12 @@ var pakoJS string
 //go:embed navigation.js
 var navigationJS string
 
+//go:embed search.css
+var searchCSS string
+
+//go:embed nav.css
+var navCSS string
+
 // ArchiveConverter handles conversion of a graph of markdown files to a single HTML archive
 type ArchiveConverter struct {
 	graph         *Graph
@@ -27,6 +35,13 @@ type ArchiveConverter struct {
 	selfContained bool
 	preload       bool
 	title         string
+	reloadSrc     string // Live reload sidecar URL for the root page (empty = disabled)
+	reloadToken   string // Live reload build token
+	tocSidebar    bool   // Render a table of contents sidebar on every page
+	tocDepth      int    // Deepest heading level in tables of contents (0 = default)
+	highlight     bool   // Highlight code at conversion time instead of with highlight.js
+	extensions    converter.Extensions
+	nav           NavLayout // Layout of the navigation sidebar listing the pages
 }
 
 // NewConverter creates a new ArchiveConverter
@@ -37,20 +52,82 @@ func NewConverter(graph *Graph, templateName string, selfContained bool, preload
 		selfContained: selfContained,
 		preload:       preload,
 		title:         title,
+		extensions:    converter.DefaultExtensions(),
+		nav:           NavByDirectory,
 	}
 }
 
+// SetLiveReload enables the live reload script on the root page of the archive.
+// See converter.Converter.SetLiveReload.
+func (ac *ArchiveConverter) SetLiveReload(src, token string) {
+	ac.reloadSrc = src
+	ac.reloadToken = token
+}
+
+// SetTOCSidebar enables the table of contents sidebar on every page of the archive
+func (ac *ArchiveConverter) SetTOCSidebar(enabled bool) {
+	ac.tocSidebar = enabled
+}
+
+// SetTOCDepth sets the deepest heading level included in tables of contents
+func (ac *ArchiveConverter) SetTOCDepth(depth int) {
+	ac.tocDepth = depth
+}
+
+// SetHighlight enables conversion-time syntax highlighting on every page of the archive.
+// See converter.Converter.SetHighlight.
+func (ac *ArchiveConverter) SetHighlight(enabled bool) {
+	ac.highlight = enabled
+}
+
+// SetExtensions selects the markdown extensions used for every page of the archive
+func (ac *ArchiveConverter) SetExtensions(ext converter.Extensions) {
+	ac.extensions = ext
+}
+
+// SetNav sets the layout of the navigation sidebar listing the pages of the archive
+func (ac *ArchiveConverter) SetNav(layout NavLayout) {
+	ac.nav = layout
+}
+
 // ConvertToArchive converts all pages in the graph and generates a single self-contained HTML archive
 func (ac *ArchiveConverter) ConvertToArchive(outputPath string) error {
+	var buf bytes.Buffer
+	if err := ac.ConvertTo(&buf); err != nil {
+		return err
+	}
+
+	// Write to output file
+	return os.WriteFile(outputPath, buf.Bytes(), 0644)
+}
+
+// ConvertTo converts all pages in the graph and writes the archive to w
+func (ac *ArchiveConverter) ConvertTo(w io.Writer) error {
 	// Convert each page to HTML and compress
 	archiveData := make(map[string]string)
 
+	// Article HTML of every page by archive key, for the search index
+	articles := make(map[string]string)
+
+	// Images of all pages, the root page's included, are stored once for the whole archive
+	assets := converter.NewAssetStore()
+
+	// The root page is converted once: its full document is the archive's page, and its
+	// article is embedded like the others for navigating back to it
+	var rootHTML string
 	for _, node := range ac.graph.OrderedNodes() {
-		// Convert to HTML (no title for embedded pages)
-		htmlContent, err := ac.convertPage(node.Path, "")
+		// Convert to HTML (only the root page gets the title)
+		title := ""
+		if node.Path == ac.graph.Root {
+			title = ac.title
+		}
+		htmlContent, err := ac.convertPage(node.Path, title, assets)
 		if err != nil {
 			return fmt.Errorf("failed to convert %s: %w", node.Path, err)
 		}
+		if node.Path == ac.graph.Root {
+			rootHTML = string(htmlContent)
+		}
 
 		// Compress with gzip
 		compressed, err := compressData(htmlContent)
@@ -63,26 +140,55 @@ func (ac *ArchiveConverter) ConvertToArchive(outputPath string) error {
 
 		// Store with relative path as key
 		archiveData[node.RelativePath] = encoded
+
+		articles[archiveKey(node.RelativePath)] = string(ExtractArticleContent(htmlContent))
 	}
 
-	// Get root HTML content (full document structure)
-	rootHTML, err := ac.convertRootPage(ac.graph.Root)
+	// Index the pages in a fixed order, so the same pages always give the same archive
+	var rootKey string
+	if rootNode := ac.graph.GetNode(ac.graph.Root); rootNode != nil {
+		rootKey = archiveKey(rootNode.RelativePath)
+	}
+	search := newSearchIndex(rootKey)
+	keys := make([]string, 0, len(articles))
+	for key := range articles {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		search.addPage(key, articles[key])
+	}
+	searchData, err := search.encode()
 	if err != nil {
-		return fmt.Errorf("failed to convert root page: %w", err)
+		return fmt.Errorf("failed to build search index: %w", err)
+	}
+
+	if rootHTML == "" {
+		return fmt.Errorf("failed to convert root page: %s is not in the graph", ac.graph.Root)
 	}
 
 	// Generate archive resources (overlay HTML, CSS, JS, archive data)
-	archiveResources := ac.generateArchiveResources(archiveData)
+	archiveResources := ac.generateArchiveResources(archiveData, searchData, assets)
 
 	// Inject archive resources before closing </body> tag
 	finalHTML := injectBeforeClosingTag(rootHTML, "</body>", archiveResources)
 
-	// Write to output file
-	return os.WriteFile(outputPath, []byte(finalHTML), 0644)
+	// Put the navigation sidebar first in the body, titling pages as the search does
+	titles := make(map[string]string, len(search.Pages))
+	for _, page := range search.Pages {
+		titles[page.Key] = page.Title
+	}
+	if items := buildNavTree(ac.graph, ac.nav, titles); items != nil {
+		finalHTML = injectAfterOpeningTag(finalHTML, "<body", renderNav(items, rootKey))
+	}
+
+	_, err = io.WriteString(w, finalHTML)
+	return err
 }
 
-// convertPage converts a single markdown file to HTML content (just the <article> content)
-func (ac *ArchiveConverter) convertPage(mdPath string, title string) ([]byte, error) {
+// convertPage converts a single markdown file to HTML content (just the <article> content).
+// Embedded images go into assets if it isn't nil.
+func (ac *ArchiveConverter) convertPage(mdPath string, title string, assets *converter.AssetStore) ([]byte, error) {
 	// Open markdown file
 	mdFile, err := os.Open(mdPath)
 	if err != nil {
@@ -99,13 +205,25 @@ func (ac *ArchiveConverter) convertPage(mdPath string, title string) ([]byte, er
 	// Create converter
 	conv := converter.New()
 	conv.SetBaseDir(filepath.Dir(mdPath))
+	conv.SetSourcePath(mdPath)
 	conv.SetSelfContained(ac.selfContained)
 	conv.SetPreload(ac.preload)
 	conv.SetArchiveMode(true) // Convert .md links to javascript:mdviewLoadPage() calls
 	conv.SetArchiveRootDir(filepath.Dir(ac.graph.Root)) // Root directory for computing archive-relative paths
+	conv.SetTOCSidebar(ac.tocSidebar)
+	conv.SetTOCDepth(ac.tocDepth)
+	conv.SetHighlight(ac.highlight)
+	conv.SetExtensions(ac.extensions)
+	if assets != nil {
+		conv.SetAssetStore(assets)
+	}
 	if title != "" {
 		conv.SetTitle(title)
 	}
+	// Only the root page keeps its footer, so only it needs the live reload script
+	if mdPath == ac.graph.Root && ac.reloadSrc != "" {
+		conv.SetLiveReload(ac.reloadSrc, ac.reloadToken)
+	}
 
 	// Convert to HTML
 	var htmlBuf bytes.Buffer
@@ -116,17 +234,10 @@ func (ac *ArchiveConverter) convertPage(mdPath string, title string) ([]byte, er
 	return htmlBuf.Bytes(), nil
 }
 
-// convertRootPage converts the root markdown file to a complete HTML document
-func (ac *ArchiveConverter) convertRootPage(mdPath string) (string, error) {
-	htmlBytes, err := ac.convertPage(mdPath, ac.title)
-	if err != nil {
-		return "", err
-	}
-	return string(htmlBytes), nil
-}
-
-// generateArchiveResources creates archive resources (JS and data for navigation)
-func (ac *ArchiveConverter) generateArchiveResources(archiveData map[string]string) string {
+// generateArchiveResources creates archive resources (JS and data for navigation).
+// searchData is the encoded search index, empty for none; assets holds the images the
+// pages reference, nil for none.
+func (ac *ArchiveConverter) generateArchiveResources(archiveData map[string]string, searchData string, assets *converter.AssetStore) string {
 	var sb strings.Builder
 
 	// 1. Add pako.js for decompression
@@ -141,16 +252,20 @@ func (ac *ArchiveConverter) generateArchiveResources(archiveData map[string]stri
 	sb.WriteString("window.mdviewArchive = {\n")
 	sb.WriteString("  pages: {\n")
 
-	// Add each page
-	first := true
-	for relPath, encodedData := range archiveData {
-		if !first {
+	// Add each page, in a fixed order so the same pages always give the same archive
+	relPaths := make([]string, 0, len(archiveData))
+	for relPath := range archiveData {
+		relPaths = append(relPaths, relPath)
+	}
+	sort.Strings(relPaths)
+	for i, relPath := range relPaths {
+		if i > 0 {
 			sb.WriteString(",\n")
 		}
-		first = false
+		encodedData := archiveData[relPath]
 
 		// Normalize path to forward slashes (must match how links are generated in converter)
-		normalizedPath := strings.ReplaceAll(relPath, "\\", "/")
+		normalizedPath := archiveKey(relPath)
 		// Escape for JavaScript string literal
 		escapedPath := strings.ReplaceAll(normalizedPath, "\"", "\\\"")
 
@@ -162,12 +277,44 @@ func (ac *ArchiveConverter) generateArchiveResources(archiveData map[string]stri
 	// Add root path (normalized with forward slashes for consistency)
 	rootPath := strings.ReplaceAll(ac.graph.Root, "\\", "/")
 	escapedRoot := strings.ReplaceAll(rootPath, "\"", "\\\"")
-	sb.WriteString(fmt.Sprintf("  root: \"%s\"\n", escapedRoot))
+	sb.WriteString(fmt.Sprintf("  root: \"%s\"", escapedRoot))
+
+	// Add the root page's archive key, which deep links map to the original page
+	if rootNode := ac.graph.GetNode(ac.graph.Root); rootNode != nil {
+		escapedKey := strings.ReplaceAll(archiveKey(rootNode.RelativePath), "\"", "\\\"")
+		sb.WriteString(fmt.Sprintf(",\n  rootKey: \"%s\"", escapedKey))
+	}
+
+	// Add search index (base64 only, so it needs no escaping)
+	if searchData != "" {
+		sb.WriteString(fmt.Sprintf(",\n  search: \"%s\"", searchData))
+	}
+
+	// Add the images pages reference by converter.AssetURLPrefix + ID (data URIs need no escaping)
+	if assets != nil {
+		if ids := assets.IDs(); len(ids) > 0 {
+			sb.WriteString(",\n  assets: {\n")
+			for i, id := range ids {
+				if i > 0 {
+					sb.WriteString(",\n")
+				}
+				asset, _ := assets.Get(id)
+				sb.WriteString(fmt.Sprintf("    \"%s\": \"%s\"", id, asset.DataURI()))
+			}
+			sb.WriteString("\n  }")
+		}
+	}
+	sb.WriteString("\n")
 
 	sb.WriteString("};\n")
 	sb.WriteString("</script>\n\n")
 
-	// 3. Add navigation.js
+	// 3. Add navigation.js and the styles of its search box and sidebar
+	sb.WriteString("<style>\n")
+	sb.WriteString(searchCSS)
+	sb.WriteString("\n")
+	sb.WriteString(navCSS)
+	sb.WriteString("\n</style>\n")
 	sb.WriteString("<script>\n")
 	sb.WriteString(navigationJS)
 	sb.WriteString("\n</script>\n")
@@ -175,6 +322,12 @@ func (ac *ArchiveConverter) generateArchiveResources(archiveData map[string]stri
 	return sb.String()
 }
 
+// archiveKey returns the key of a page in the archive: its relative path with forward
+// slashes, as used in the javascript:mdviewLoadPage() links the converter generates
+func archiveKey(relPath string) string {
+	return strings.ReplaceAll(relPath, "\\", "/")
+}
+
 // compressData compresses data using gzip
 func compressData(data []byte) ([]byte, error) {
 	var buf bytes.Buffer
@@ -202,6 +355,23 @@ func injectBeforeClosingTag(html, closingTag, content string) string {
 	return html[:index] + content + html[index:]
 }
 
+// injectAfterOpeningTag finds the first occurrence of an opening tag (e.g. "<body") and injects
+// content right after it
+func injectAfterOpeningTag(html, openingTag, content string) string {
+	index := strings.Index(html, openingTag)
+	if index == -1 {
+		// If tag not found, just prepend
+		return content + html
+	}
+	end := strings.Index(html[index:], ">")
+	if end == -1 {
+		return content + html
+	}
+
+	index += end + 1
+	return html[:index] + "\n" + content + html[index:]
+}
+
 // ConvertToArchiveWithTemplate is a convenience function that loads the template and converts
 func ConvertToArchiveWithTemplate(graph *Graph, outputPath, templateName string, selfContained, preload bool, title string) error {
 	// Validate template exists
@@ -1,10 +1,18 @@
 package archive
 
 import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"testing"
+
+	"mdview/converter"
 )
 
 func TestCompressData(t *testing.T) {
@@ -164,7 +172,7 @@ func TestArchiveConverter_GenerateArchiveResources(t *testing.T) {
 		"root.md": "dGVzdCBkYXRh", // base64 "test data"
 	}
 
-	resources := ac.generateArchiveResources(archiveData)
+	resources := ac.generateArchiveResources(archiveData, "", nil)
 
 	// Verify all required components are present
 	requiredComponents := []string{
@@ -188,6 +196,11 @@ func TestArchiveConverter_GenerateArchiveResources(t *testing.T) {
 	if !strings.Contains(resources, "C:/test/root.md") {
 		t.Error("generateArchiveResources() does not contain root path")
 	}
+
+	// Verify the root page's archive key is embedded for deep links
+	if !strings.Contains(resources, `rootKey: "root.md"`) {
+		t.Error("generateArchiveResources() does not contain root key")
+	}
 }
 
 func TestArchiveConverter_ConvertToArchive(t *testing.T) {
@@ -271,6 +284,44 @@ func TestArchiveConverter_ConvertToArchive(t *testing.T) {
 	}
 }
 
+func TestArchiveConverter_ConvertTo(t *testing.T) {
+	tempDir := t.TempDir()
+
+	rootPath := filepath.Join(tempDir, "root.md")
+	if err := os.WriteFile(rootPath, []byte("# Root\n\n[Link to A](a.md)\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "a.md"), []byte("# Page A\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	graph, err := BuildGraph(rootPath, 10)
+	if err != nil {
+		t.Fatalf("BuildGraph() error = %v", err)
+	}
+
+	// Writing to a writer must produce the same archive as writing to a file
+	var buf bytes.Buffer
+	if err := NewConverter(graph, "default", true, false, "").ConvertTo(&buf); err != nil {
+		t.Fatalf("ConvertTo() error = %v", err)
+	}
+	outputPath := filepath.Join(tempDir, "archive.html")
+	if err := NewConverter(graph, "default", true, false, "").ConvertToArchive(outputPath); err != nil {
+		t.Fatalf("ConvertToArchive() error = %v", err)
+	}
+	output, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read output file: %v", err)
+	}
+
+	if !strings.Contains(buf.String(), "window.mdviewArchive") || !strings.HasSuffix(buf.String(), "</html>\n") {
+		t.Error("ConvertTo() output is not a complete archive")
+	}
+	if len(output) != buf.Len() {
+		t.Errorf("ConvertTo() wrote %d bytes, ConvertToArchive() %d", buf.Len(), len(output))
+	}
+}
+
 func TestWriteArchive(t *testing.T) {
 	tempDir := t.TempDir()
 
@@ -361,7 +412,7 @@ func TestArchiveConverter_PathEscaping(t *testing.T) {
 		"path\"with\"quotes.md":    "data2",
 	}
 
-	resources := ac.generateArchiveResources(archiveData)
+	resources := ac.generateArchiveResources(archiveData, "", nil)
 
 	// Verify backslashes are normalized to forward slashes (to match link generation)
 	if !strings.Contains(resources, "path/with/backslash.md") {
@@ -567,3 +618,141 @@ func TestWriteArchive_TitleFromFilenameWithHyphens(t *testing.T) {
 		t.Errorf("WriteArchive() should preserve hyphens in title, got:\n%s", outputStr)
 	}
 }
+
+func TestArchiveConverter_LiveReload(t *testing.T) {
+	tempDir := t.TempDir()
+
+	rootPath := createTestFile(t, tempDir, "root.md", "# Root\n\n[Link to A](a.md)\n")
+	createTestFile(t, tempDir, "a.md", "# Page A\n")
+
+	graph, err := BuildGraph(rootPath, 10)
+	if err != nil {
+		t.Fatalf("BuildGraph() error = %v", err)
+	}
+
+	ac := NewConverter(graph, "default", true, false, "")
+	ac.SetLiveReload("archive.html.reload.js", "token42")
+
+	outputPath := filepath.Join(tempDir, "archive.html")
+	if err := ac.ConvertToArchive(outputPath); err != nil {
+		t.Fatalf("ConvertToArchive() error = %v", err)
+	}
+
+	output, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read output file: %v", err)
+	}
+
+	if strings.Count(string(output), `window.mdviewLiveReload = {src: "archive.html.reload.js", token: "token42"}`) != 1 {
+		t.Error("expected live reload script exactly once on the root page")
+	}
+}
+
+func TestArchiveConverter_SharesImages(t *testing.T) {
+	tempDir := t.TempDir()
+	logo := bytes.Repeat([]byte("logo-bytes"), 100)
+	if err := os.WriteFile(filepath.Join(tempDir, "logo.png"), logo, 0644); err != nil {
+		t.Fatal(err)
+	}
+	pages := map[string]string{
+		"root.md": "# Root\n\n![logo](logo.png)\n\n[A](a.md) [B](b.md)\n",
+		"a.md":    "# A\n\n![logo](logo.png)\n",
+		"b.md":    "# B\n\n![logo](logo.png)\n",
+	}
+	for name, content := range pages {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	graph, err := BuildGraph(filepath.Join(tempDir, "root.md"), 10)
+	if err != nil {
+		t.Fatalf("BuildGraph() error = %v", err)
+	}
+	var buf bytes.Buffer
+	if err := NewConverter(graph, "default", true, false, "").ConvertTo(&buf); err != nil {
+		t.Fatalf("ConvertTo() error = %v", err)
+	}
+	output := buf.String()
+
+	// Only in the asset table, which the root page shares with the embedded pages
+	dataURI := converter.Asset{MimeType: "image/png", Data: logo}.DataURI()
+	if count := strings.Count(output, dataURI); count != 1 {
+		t.Errorf("expected the image embedded once, got %d", count)
+	}
+	if !strings.Contains(output, "  assets: {\n    \"") {
+		t.Error("expected an asset table in the archive data")
+	}
+
+	// The root page is converted once and references the asset too
+	if !strings.Contains(output, `<img src="`+converter.AssetURLPrefix) {
+		t.Error("expected the root page to reference the shared image")
+	}
+
+	// Embedded pages reference the asset instead of carrying it
+	for _, key := range []string{"root.md", "a.md", "b.md"} {
+		content, err := decodeArchivePage(output, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(content, `src="`+converter.AssetURLPrefix) || strings.Contains(content, "data:image/png") {
+			t.Errorf("expected %s to reference the shared image", key)
+		}
+	}
+}
+
+func TestArchiveConverter_Deterministic(t *testing.T) {
+	tempDir := t.TempDir()
+	for _, name := range []string{"one.png", "two.png", "three.png"} {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pages := map[string]string{
+		"root.md": "# Root\n\n![one](one.png)\n\n[A](a.md) [B](b.md) [C](c.md)\n",
+		"a.md":    "# A\n\n![two](two.png)\n",
+		"b.md":    "# B\n\n![three](three.png)\n",
+		"c.md":    "# C\n\n![one](one.png)\n",
+	}
+	for name, content := range pages {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var outputs []string
+	for i := 0; i < 5; i++ {
+		graph, err := BuildGraph(filepath.Join(tempDir, "root.md"), 10)
+		if err != nil {
+			t.Fatalf("BuildGraph() error = %v", err)
+		}
+		var buf bytes.Buffer
+		if err := NewConverter(graph, "default", true, false, "").ConvertTo(&buf); err != nil {
+			t.Fatalf("ConvertTo() error = %v", err)
+		}
+		outputs = append(outputs, buf.String())
+	}
+	for i := 1; i < len(outputs); i++ {
+		if outputs[i] != outputs[0] {
+			t.Fatalf("expected the same input to give the same archive, run %d differs", i)
+		}
+	}
+}
+
+// decodeArchivePage returns the decompressed HTML of a page embedded in archive output
+func decodeArchivePage(output, key string) (string, error) {
+	match := regexp.MustCompile(`"` + regexp.QuoteMeta(key) + `": "([^"]+)"`).FindStringSubmatch(output)
+	if match == nil {
+		return "", fmt.Errorf("page %s not found in archive", key)
+	}
+	compressed, err := base64.StdEncoding.DecodeString(match[1])
+	if err != nil {
+		return "", err
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return "", err
+	}
+	data, err := io.ReadAll(reader)
+	return string(data), err
+}
@@ -0,0 +1,215 @@
+package archive
+
+import (
+	"fmt"
+	htmlpkg "html"
+	"path"
+	"sort"
+	"strings"
+
+	"mdview/converter"
+)
+
+// NavLayout selects how the pages of an archive are arranged in its navigation sidebar
+type NavLayout string
+
+const (
+	NavByDirectory NavLayout = "dirs"  // Mirror the directories of the markdown files
+	NavByLinks     NavLayout = "links" // Nest each page under the page it was first linked from
+	NavNone        NavLayout = "none"  // No navigation sidebar
+)
+
+// ParseNavLayout parses the value of the --nav flag
+func ParseNavLayout(s string) (NavLayout, error) {
+	switch layout := NavLayout(strings.ToLower(strings.TrimSpace(s))); layout {
+	case NavByDirectory, NavByLinks, NavNone:
+		return layout, nil
+	}
+	return "", fmt.Errorf("unknown navigation layout %q (use dirs, links or none)", s)
+}
+
+// navIndexNames are the file names of a directory's own page, in order of preference
+var navIndexNames = []string{"README.md", "readme.md", "index.md"}
+
+// navItem is an entry of the navigation tree: a page, a directory, or a directory
+// whose index page opens when its label is clicked
+type navItem struct {
+	key      string // Archive key of the page ("" for a directory without a page)
+	label    string
+	dir      bool
+	children []*navItem
+}
+
+// buildNavTree arranges the pages of graph in layout. titles maps archive keys to
+// page titles. It returns nil if there is nothing to navigate between.
+func buildNavTree(graph *Graph, layout NavLayout, titles map[string]string) []*navItem {
+	if graph.Count < 2 {
+		return nil
+	}
+	switch layout {
+	case NavByDirectory:
+		return navByDirectory(graph, titles)
+	case NavByLinks:
+		return navByLinks(graph, titles)
+	}
+	return nil
+}
+
+// navByDirectory returns the root page followed by the other pages in a tree of their
+// directories. In every directory, pages come before subdirectories, both sorted by name.
+func navByDirectory(graph *Graph, titles map[string]string) []*navItem {
+	rootKey := archiveKey(graph.GetNode(graph.Root).RelativePath)
+	top := &navItem{dir: true}
+	dirs := map[string]*navItem{"": top}
+
+	// Directory of a key, created along with its parents on first use
+	var dirItem func(dir string) *navItem
+	dirItem = func(dir string) *navItem {
+		if item, ok := dirs[dir]; ok {
+			return item
+		}
+		parent := dirItem(parentDir(dir))
+		item := &navItem{label: path.Base(dir), dir: true}
+		parent.children = append(parent.children, item)
+		dirs[dir] = item
+		return item
+	}
+
+	keys := make([]string, 0, graph.Count)
+	for _, node := range graph.Nodes {
+		if key := archiveKey(node.RelativePath); key != rootKey {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	pages := map[string]bool{}
+	for _, key := range keys {
+		pages[key] = true
+	}
+
+	for _, key := range keys {
+		dir := parentDir(key)
+		// A directory's index page is opened by the directory's label instead of being listed
+		if dir != "" && navIndexPage(dir, pages) == key {
+			dirItem(dir).key = key
+			continue
+		}
+		dirItem(dir).children = append(dirItem(dir).children, &navItem{key: key, label: titles[key]})
+	}
+
+	sortNavItems(top)
+	return append([]*navItem{{key: rootKey, label: titles[rootKey]}}, top.children...)
+}
+
+// navIndexPage returns the key of the index page of dir, or "" if it has none in the archive
+func navIndexPage(dir string, pages map[string]bool) string {
+	for _, name := range navIndexNames {
+		if key := dir + "/" + name; pages[key] {
+			return key
+		}
+	}
+	return ""
+}
+
+// parentDir returns the directory part of an archive key ("" at the top level)
+func parentDir(key string) string {
+	if i := strings.LastIndex(key, "/"); i != -1 {
+		return key[:i]
+	}
+	return ""
+}
+
+// sortNavItems puts the pages of a directory tree before its subdirectories, each sorted
+// by name; pages were added in key order, so a stable sort keeps them sorted
+func sortNavItems(dir *navItem) {
+	sort.SliceStable(dir.children, func(i, j int) bool {
+		a, b := dir.children[i], dir.children[j]
+		if a.dir != b.dir {
+			return !a.dir
+		}
+		return a.dir && a.label < b.label
+	})
+	for _, child := range dir.children {
+		if child.dir {
+			sortNavItems(child)
+		}
+	}
+}
+
+// navByLinks returns the root page followed by the pages it links to, with every page
+// nested under the page the archive builder discovered it from, in link order
+func navByLinks(graph *Graph, titles map[string]string) []*navItem {
+	root := graph.GetNode(graph.Root)
+	items := map[string]*navItem{
+		root.Path: {key: archiveKey(root.RelativePath), label: titles[archiveKey(root.RelativePath)]},
+	}
+
+	// Repeat the builder's breadth-first search to find where each page was discovered
+	queue := []*Node{root}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		parent := items[node.Path]
+
+		for _, link := range node.Links {
+			child := graph.GetNode(link)
+			if child == nil || items[link] != nil {
+				continue
+			}
+			item := &navItem{key: archiveKey(child.RelativePath), label: titles[archiveKey(child.RelativePath)]}
+			items[link] = item
+			parent.children = append(parent.children, item)
+			queue = append(queue, child)
+		}
+	}
+
+	rootItem := items[root.Path]
+	return append([]*navItem{{key: rootItem.key, label: rootItem.label}}, rootItem.children...)
+}
+
+// renderNav returns the navigation sidebar HTML for items. The root page's link returns
+// to the original page; every other link loads its page from the archive.
+func renderNav(items []*navItem, rootKey string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "<nav class=\"mdview-nav\" aria-label=\"Pages\" data-root=\"%s\">\n", htmlpkg.EscapeString(rootKey))
+	sb.WriteString("<div class=\"mdview-nav-title\">Pages</div>\n")
+	renderNavList(&sb, items, rootKey)
+	sb.WriteString("</nav>\n")
+	return sb.String()
+}
+
+// renderNavList writes items as a nested list; entries with children are collapsible
+func renderNavList(sb *strings.Builder, items []*navItem, rootKey string) {
+	sb.WriteString("<ul>\n")
+	for _, item := range items {
+		sb.WriteString("<li>")
+		if len(item.children) > 0 {
+			sb.WriteString("<details><summary>")
+			writeNavLabel(sb, item, rootKey)
+			sb.WriteString("</summary>\n")
+			renderNavList(sb, item.children, rootKey)
+			sb.WriteString("</details>")
+		} else {
+			writeNavLabel(sb, item, rootKey)
+		}
+		sb.WriteString("</li>\n")
+	}
+	sb.WriteString("</ul>\n")
+}
+
+// writeNavLabel writes the label of item, as a link if it has a page
+func writeNavLabel(sb *strings.Builder, item *navItem, rootKey string) {
+	label := htmlpkg.EscapeString(item.label)
+	if item.key == "" {
+		sb.WriteString(label)
+		return
+	}
+
+	href := "javascript:mdviewLoadOriginal()"
+	if item.key != rootKey {
+		href = "javascript:mdviewLoadPage('" + converter.EscapeJSString(item.key) + "')"
+	}
+	fmt.Fprintf(sb, "<a href=\"%s\" data-page=\"%s\">%s</a>",
+		htmlpkg.EscapeString(href), htmlpkg.EscapeString(item.key), label)
+}
@@ -0,0 +1,183 @@
+package archive
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeNavFixture writes a small documentation tree and returns the path of its root page
+func writeNavFixture(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"README.md":         "# Home\n\n- [Guide](guide/README.md)\n- [DB](db.md)\n- [Auth](docs/api/auth.md)\n",
+		"db.md":             "# Database\n",
+		"guide/README.md":   "# Guide\n\n[Install](install.md)\n",
+		"guide/install.md":  "# Install\n\n[Auth](../docs/api/auth.md)\n",
+		"docs/api/auth.md":  "# Auth API\n\n[Users](users.md)\n",
+		"docs/api/users.md": "No heading here.\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return filepath.Join(dir, "README.md")
+}
+
+// navOutline returns items as indented "key label" lines, for comparing trees
+func navOutline(items []*navItem) string {
+	var sb strings.Builder
+	var walk func(items []*navItem, indent string)
+	walk = func(items []*navItem, indent string) {
+		for _, item := range items {
+			sb.WriteString(indent + item.key + " " + item.label + "\n")
+			walk(item.children, indent+"  ")
+		}
+	}
+	walk(items, "")
+	return sb.String()
+}
+
+var navFixtureTitles = map[string]string{
+	"README.md":         "Home",
+	"db.md":             "Database",
+	"guide/README.md":   "Guide",
+	"guide/install.md":  "Install",
+	"docs/api/auth.md":  "Auth API",
+	"docs/api/users.md": "users",
+}
+
+func TestBuildNavTree_ByDirectory(t *testing.T) {
+	graph, err := BuildGraph(writeNavFixture(t), 10)
+	if err != nil {
+		t.Fatalf("BuildGraph() error = %v", err)
+	}
+
+	got := navOutline(buildNavTree(graph, NavByDirectory, navFixtureTitles))
+	want := `README.md Home
+db.md Database
+ docs
+   api
+    docs/api/auth.md Auth API
+    docs/api/users.md users
+guide/README.md guide
+  guide/install.md Install
+`
+	if got != want {
+		t.Errorf("unexpected tree:\n got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestBuildNavTree_ByLinks(t *testing.T) {
+	graph, err := BuildGraph(writeNavFixture(t), 10)
+	if err != nil {
+		t.Fatalf("BuildGraph() error = %v", err)
+	}
+
+	got := navOutline(buildNavTree(graph, NavByLinks, navFixtureTitles))
+	want := `README.md Home
+guide/README.md Guide
+  guide/install.md Install
+db.md Database
+docs/api/auth.md Auth API
+  docs/api/users.md users
+`
+	if got != want {
+		t.Errorf("unexpected tree:\n got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestBuildNavTree_NoneOrSinglePage(t *testing.T) {
+	graph, err := BuildGraph(writeNavFixture(t), 10)
+	if err != nil {
+		t.Fatalf("BuildGraph() error = %v", err)
+	}
+	if items := buildNavTree(graph, NavNone, navFixtureTitles); items != nil {
+		t.Errorf("expected no tree for NavNone, got %d items", len(items))
+	}
+
+	single, err := BuildGraph(writeNavFixture(t), 1)
+	if err != nil {
+		t.Fatalf("BuildGraph() error = %v", err)
+	}
+	if items := buildNavTree(single, NavByDirectory, navFixtureTitles); items != nil {
+		t.Errorf("expected no tree for a single page, got %d items", len(items))
+	}
+}
+
+func TestRenderNav(t *testing.T) {
+	items := []*navItem{
+		{key: "README.md", label: "Home"},
+		{label: "docs", dir: true, children: []*navItem{
+			{key: "docs/it's <new>.md", label: "It's <new>"},
+		}},
+	}
+	got := renderNav(items, "README.md")
+
+	for _, want := range []string{
+		`data-root="README.md"`,
+		`<a href="javascript:mdviewLoadOriginal()" data-page="README.md">Home</a>`,
+		`<details><summary>docs</summary>`,
+		`<a href="javascript:mdviewLoadPage(&#39;docs/it\&#39;s &lt;new&gt;.md&#39;)" data-page="docs/it&#39;s &lt;new&gt;.md">It&#39;s &lt;new&gt;</a>`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %s in:\n%s", want, got)
+		}
+	}
+}
+
+func TestParseNavLayout(t *testing.T) {
+	for _, s := range []string{"dirs", "links", "none", " Links "} {
+		if _, err := ParseNavLayout(s); err != nil {
+			t.Errorf("ParseNavLayout(%q) error = %v", s, err)
+		}
+	}
+	if _, err := ParseNavLayout("tree"); err == nil {
+		t.Error("expected an error for an unknown layout")
+	}
+}
+
+func TestArchiveConverter_EmbedsNav(t *testing.T) {
+	graph, err := BuildGraph(writeNavFixture(t), 10)
+	if err != nil {
+		t.Fatalf("BuildGraph() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := NewConverter(graph, "default", true, false, "").ConvertTo(&buf); err != nil {
+		t.Fatalf("ConvertTo() error = %v", err)
+	}
+	html := buf.String()
+
+	navStart := strings.Index(html, `<nav class="mdview-nav"`)
+	if navStart == -1 {
+		t.Fatal("expected a navigation sidebar in the archive")
+	}
+	if bodyStart := strings.Index(html, "<body"); navStart < bodyStart || navStart > strings.Index(html, "<article") {
+		t.Error("expected the navigation sidebar at the start of the body")
+	}
+	if !strings.Contains(html, `data-page="docs/api/auth.md">Auth API</a>`) {
+		t.Error("expected pages to be labelled with their titles")
+	}
+	if !strings.Contains(html, ".mdview-nav") {
+		t.Error("expected the sidebar styles in the archive")
+	}
+
+	buf.Reset()
+	ac := NewConverter(graph, "default", true, false, "")
+	ac.SetNav(NavNone)
+	if err := ac.ConvertTo(&buf); err != nil {
+		t.Fatalf("ConvertTo() error = %v", err)
+	}
+	if strings.Contains(buf.String(), `<nav class="mdview-nav"`) {
+		t.Error("expected no navigation sidebar with NavNone")
+	}
+}
@@ -6,6 +6,7 @@
 
   // State
   var originalContent = null;  // Saved original page content
+  var originalSidebar = null;  // Saved original table of contents sidebar
   var currentPage = null;      // Current embedded page key (null = original)
 
   // Decompression function
@@ -38,47 +39,137 @@
     return html.substring(contentStart, endIdx);
   }
 
+  // Extract the table of contents sidebar innerHTML from full HTML ('' if none)
+  function extractSidebarContent(html) {
+    var startTag = '<nav class="toc toc-sidebar">';
+    var endTag = '</nav>';
+
+    var startIdx = html.indexOf(startTag);
+    if (startIdx === -1) return '';
+
+    var contentStart = startIdx + startTag.length;
+    var endIdx = html.indexOf(endTag, contentStart);
+    if (endIdx === -1) return '';
+
+    return html.substring(contentStart, endIdx);
+  }
+
+  // Shared images: pages reference them as "mdview-asset:<id>" (see converter.AssetURLPrefix)
+  var assetURLs = {};          // Asset ID -> URL of the decoded image
+  var assetPattern = /mdview-asset:([0-9a-f]+)/g;
+
+  // Return a URL for an image of the archive's asset table, decoding it once into a Blob
+  // so pages sharing it don't each carry a copy ('' if there is no such asset)
+  function assetURL(id) {
+    if (assetURLs[id]) return assetURLs[id];
+
+    var dataURI = window.mdviewArchive.assets && window.mdviewArchive.assets[id];
+    if (!dataURI) return '';
+
+    var url = dataURI;
+    try {
+      var comma = dataURI.indexOf(',');
+      var type = dataURI.substring(5, dataURI.indexOf(';'));
+      var decoded = atob(dataURI.substring(comma + 1));
+      var bytes = new Uint8Array(decoded.length);
+      for (var i = 0; i < decoded.length; i++) {
+        bytes[i] = decoded.charCodeAt(i);
+      }
+      url = URL.createObjectURL(new Blob([bytes], { type: type }));
+    } catch (e) {
+      // Fall back to the data URI itself
+    }
+    assetURLs[id] = url;
+    return url;
+  }
+
+  // Replace the asset references in page HTML with image URLs
+  function resolveAssets(html) {
+    return html.replace(assetPattern, function(ref, id) {
+      return assetURL(id) || ref;
+    });
+  }
+
   // Get the main article element
   function getArticle() {
     return document.querySelector('article.markdown-body');
   }
 
-  // Global function to load a page from the archive
-  window.mdviewLoadPage = function(archiveKey) {
+  // Get the table of contents sidebar (null if the page has none)
+  function getSidebar() {
+    return document.querySelector('nav.toc-sidebar');
+  }
+
+  // Apply the reader's light/dark choice (from the template's theme toggle, if it has one)
+  // to newly inserted content, e.g. <picture> sources chosen by color scheme
+  function applyTheme(article) {
+    if (window.mdviewTheme) {
+      window.mdviewTheme.apply(article);
+    }
+  }
+
+  // Mark the current page in the navigation sidebar and expand the entries above it
+  function highlightNav() {
+    var nav = document.querySelector('nav.mdview-nav');
+    if (!nav) return;
+
+    var key = currentPage === null ? nav.getAttribute('data-root') : currentPage;
+    var links = nav.querySelectorAll('a[data-page]');
+    for (var i = 0; i < links.length; i++) {
+      if (links[i].getAttribute('data-page') !== key) {
+        links[i].removeAttribute('aria-current');
+        continue;
+      }
+      links[i].setAttribute('aria-current', 'page');
+      for (var el = links[i].parentNode; el && el !== nav; el = el.parentNode) {
+        if (el.tagName === 'DETAILS') el.open = true;
+      }
+    }
+  }
+
+  // Show a page of the archive in place of the current one (false if it can't be loaded)
+  function showPage(archiveKey) {
     if (!window.mdviewArchive || !window.mdviewArchive.pages) {
       console.error('Archive data not available');
-      return;
+      return false;
     }
 
     var article = getArticle();
     if (!article) {
       console.error('Article element not found');
-      return;
+      return false;
     }
 
+    var sidebar = getSidebar();
+
     // Save original content on first navigation
     if (originalContent === null) {
       originalContent = article.innerHTML;
+      originalSidebar = sidebar ? sidebar.innerHTML : null;
     }
 
     // Look up in archive
     var compressed = window.mdviewArchive.pages[archiveKey];
     if (!compressed) {
       console.warn('Page not found in archive:', archiveKey);
-      return;
+      return false;
     }
 
     // Decompress
     var html = decompressPage(compressed);
     if (!html) {
       console.error('Failed to decompress page:', archiveKey);
-      return;
+      return false;
     }
 
     // Extract and replace content
-    var content = extractArticleContent(html);
+    var content = resolveAssets(extractArticleContent(html));
     article.innerHTML = content;
+    if (sidebar) {
+      sidebar.innerHTML = extractSidebarContent(html);
+    }
     currentPage = archiveKey;
+    highlightNav();
 
     // Re-initialize syntax highlighting if available
     if (window.hljs) {
@@ -86,20 +177,24 @@
         hljs.highlightBlock(block);
       });
     }
+    applyTheme(article);
+    return true;
+  }
 
-    // Scroll to top
-    window.scrollTo(0, 0);
-  };
-
-  // Global function to return to original page
-  window.mdviewLoadOriginal = function() {
+  // Show the original page again
+  function showOriginal() {
     if (originalContent === null) return;
 
     var article = getArticle();
     if (!article) return;
 
     article.innerHTML = originalContent;
+    var sidebar = getSidebar();
+    if (sidebar && originalSidebar !== null) {
+      sidebar.innerHTML = originalSidebar;
+    }
     currentPage = null;
+    highlightNav();
 
     // Re-initialize syntax highlighting if available
     if (window.hljs) {
@@ -107,19 +202,415 @@
         hljs.highlightBlock(block);
       });
     }
+    applyTheme(article);
+  }
+
+  // Scroll to the heading or other element with the given id, or to the top without one
+  function scrollToAnchor(anchor) {
+    var target = anchor ? document.getElementById(anchor) : null;
+    if (target) {
+      target.scrollIntoView();
+    } else {
+      window.scrollTo(0, 0);
+    }
+  }
+
+  // History
+  //
+  // The URL hash records where the reader is, so Back/Forward move between pages and a
+  // page can be bookmarked or shared: "#/docs/setup.md" is an embedded page,
+  // "#/docs/setup.md#install" a heading on it. The original (root) page keeps a plain
+  // hash: none at all, or "#install" for one of its headings.
+
+  // Return the URL hash of a location in the archive (key null = the original page)
+  function routeHash(key, anchor) {
+    if (key === null) {
+      return anchor ? '#' + anchor : '';
+    }
+    var path = key.split('/').map(encodeURIComponent).join('/');
+    return '#/' + path + (anchor ? '#' + anchor : '');
+  }
+
+  // Parse a URL hash into the archive location it records
+  function parseRoute(hash) {
+    hash = hash.replace(/^#/, '');
+    if (hash.charAt(0) !== '/') {
+      return { key: null, anchor: decodeHashPart(hash) };
+    }
+
+    var sep = hash.indexOf('#');
+    var path = sep === -1 ? hash.substring(1) : hash.substring(1, sep);
+    var key = decodeHashPart(path);
+    var rootKey = window.mdviewArchive && window.mdviewArchive.rootKey;
+    return {
+      key: key === rootKey ? null : key,
+      anchor: sep === -1 ? '' : decodeHashPart(hash.substring(sep + 1))
+    };
+  }
+
+  function decodeHashPart(part) {
+    try {
+      return decodeURIComponent(part);
+    } catch (e) {
+      return part;
+    }
+  }
+
+  // Record a location in the browser history (replacing the current entry if replace is set)
+  function setRoute(key, anchor, replace) {
+    var url = location.pathname + location.search + routeHash(key, anchor);
+    try {
+      if (replace) {
+        history.replaceState(null, '', url);
+      } else {
+        history.pushState(null, '', url);
+      }
+    } catch (e) {
+      // Some browsers restrict history changes for file:// pages; navigation still works
+      console.warn('Failed to update history:', e);
+    }
+  }
 
-    // Scroll to top
-    window.scrollTo(0, 0);
+  // Show a location of the archive (key null = the original page) without touching history
+  function showRoute(key, anchor) {
+    if (key === null) {
+      showOriginal();
+    } else if (key !== currentPage && !showPage(key)) {
+      return false;
+    }
+    scrollToAnchor(anchor);
+    return true;
+  }
+
+  // Go to a location of the archive, adding it to the browser history unless the
+  // reader is already there
+  function navigate(key, anchor) {
+    var rootKey = window.mdviewArchive && window.mdviewArchive.rootKey;
+    if (key === rootKey) key = null;
+    anchor = anchor || '';
+    if (showRoute(key, anchor)) {
+      setRoute(key, anchor, routeHash(key, anchor) === location.hash);
+    }
+  }
+
+  // Global function to load a page from the archive, scrolled to the element with id
+  // anchor if given (links to "page.md#heading" pass the heading's id)
+  window.mdviewLoadPage = function(archiveKey, anchor) {
+    navigate(archiveKey, anchor);
   };
 
+  // Global function to return to original page
+  window.mdviewLoadOriginal = function() {
+    navigate(null, '');
+  };
+
+  // Show the location in the URL, e.g. after Back/Forward or when opening a deep link
+  function restoreRoute() {
+    var route = parseRoute(location.hash);
+    if (route.key === null && currentPage === null) {
+      // Plain anchors on the original page are the browser's job
+      return;
+    }
+    if (!showRoute(route.key, route.anchor)) {
+      // The page isn't in the archive (e.g. an outdated bookmark): stay on the original page
+      showRoute(null, '');
+      setRoute(null, '', true);
+    }
+  }
+
+  // Keep in-page links (table of contents, footnotes) on an embedded page: a plain
+  // "#anchor" in the URL would mean the original page
+  function handleAnchorClick(e) {
+    if (currentPage === null || e.defaultPrevented || e.button !== 0 ||
+        e.ctrlKey || e.metaKey || e.shiftKey || e.altKey) return;
+
+    var link = e.target.closest ? e.target.closest('a[href^="#"]') : null;
+    if (!link || link.closest('.mdview-search')) return;
+
+    var href = link.getAttribute('href');
+    if (href.charAt(1) === '/') return;
+
+    e.preventDefault();
+    var anchor = decodeHashPart(href.substring(1));
+    scrollToAnchor(anchor);
+    setRoute(currentPage, anchor, false);
+  }
+
+  function initHistory() {
+    window.addEventListener('popstate', restoreRoute);
+    document.addEventListener('click', handleAnchorClick);
+
+    // Open the page a deep link points to
+    var route = parseRoute(location.hash);
+    if (route.key !== null) {
+      restoreRoute();
+    }
+  }
+
+  // Search
+  var searchIndex = null;      // Decompressed search index (loaded on first use)
+  var maxResults = 20;
+  var minTermLength = 2;       // Must match minTermLength in search.go
+
+  // Load the search index embedded by the archive builder (null if there is none)
+  function getSearchIndex() {
+    if (searchIndex === null && window.mdviewArchive && window.mdviewArchive.search) {
+      var json = decompressPage(window.mdviewArchive.search);
+      if (json) {
+        searchIndex = JSON.parse(json);
+      }
+    }
+    return searchIndex;
+  }
+
+  // Split text into lowercase terms, the same way searchTerms in search.go does
+  function tokenize(text) {
+    return text.toLowerCase().split(/[^\p{L}\p{N}]+/u).filter(function(word) {
+      return Array.from(word).length >= minTermLength;
+    });
+  }
+
+  // Find the sections containing every query word (as a prefix of an indexed term)
+  // and return the best section of each matching page, best pages first
+  function search(query) {
+    var index = getSearchIndex();
+    var words = tokenize(query);
+    if (!index || words.length === 0) return [];
+
+    var pageScores = null;     // page -> score, for pages matching every word so far
+    var sectionScores = {};    // "page:section" -> score
+    var terms = Object.keys(index.terms);
+
+    words.forEach(function(word) {
+      var wordPages = {};
+      terms.forEach(function(term) {
+        if (term.indexOf(word) !== 0) return;
+        var weight = term === word ? 2 : 1;
+        var postings = index.terms[term];
+        for (var i = 0; i < postings.length; i += 2) {
+          var key = postings[i] + ':' + postings[i + 1];
+          sectionScores[key] = (sectionScores[key] || 0) + weight;
+          wordPages[postings[i]] = (wordPages[postings[i]] || 0) + weight;
+        }
+      });
+
+      if (pageScores === null) {
+        pageScores = wordPages;
+        return;
+      }
+      var both = {};
+      Object.keys(pageScores).forEach(function(page) {
+        if (wordPages[page]) both[page] = pageScores[page] + wordPages[page];
+      });
+      pageScores = both;
+    });
+
+    // Pick the best scoring section of each page
+    var best = {};
+    Object.keys(sectionScores).forEach(function(key) {
+      var parts = key.split(':');
+      var page = parts[0];
+      if (!pageScores[page]) return;
+      if (!best[page] || sectionScores[key] > best[page].score) {
+        best[page] = { page: +page, section: +parts[1], score: sectionScores[key] };
+      }
+    });
+
+    return Object.keys(best).map(function(page) {
+      var hit = best[page];
+      hit.score = pageScores[page];
+      return hit;
+    }).sort(function(a, b) {
+      return b.score - a.score || a.page - b.page;
+    }).slice(0, maxResults);
+  }
+
+  // Append text to parent with the query words marked
+  function appendMarked(parent, text, words) {
+    var lower = text.toLowerCase();
+    var pos = 0;
+    while (pos < text.length) {
+      var next = -1, length = 0;
+      words.forEach(function(word) {
+        var i = lower.indexOf(word, pos);
+        if (i !== -1 && (next === -1 || i < next)) {
+          next = i;
+          length = word.length;
+        }
+      });
+      if (next === -1) break;
+      parent.appendChild(document.createTextNode(text.substring(pos, next)));
+      var mark = document.createElement('mark');
+      mark.textContent = text.substr(next, length);
+      parent.appendChild(mark);
+      pos = next + length;
+    }
+    parent.appendChild(document.createTextNode(text.substring(pos)));
+  }
+
+  // Open a search result: its page, scrolled to the heading of the matching section
+  function openResult(page, section) {
+    var index = getSearchIndex();
+    var key = index.pages[page].key;
+    var id = index.pages[page].sections[section].id;
+
+    navigate(key === index.root ? null : key, id);
+  }
+
+  // Add the search box to the page
+  function initSearch() {
+    if (!window.mdviewArchive || !window.mdviewArchive.search) return;
+
+    var box = document.createElement('div');
+    box.className = 'mdview-search';
+    box.setAttribute('role', 'search');
+    if (document.querySelector('.theme-toggle')) {
+      box.className += ' mdview-search-beside-toggle';
+    }
+
+    var input = document.createElement('input');
+    input.type = 'search';
+    input.placeholder = 'Search pages (press /)';
+    input.setAttribute('aria-label', 'Search all pages');
+    input.setAttribute('autocomplete', 'off');
+
+    var list = document.createElement('ul');
+    list.className = 'mdview-search-results';
+    list.setAttribute('role', 'listbox');
+    list.hidden = true;
+
+    box.appendChild(input);
+    box.appendChild(list);
+    document.body.appendChild(box);
+
+    var results = [];
+    var selected = -1;
+
+    function select(i) {
+      var items = list.querySelectorAll('li[role="option"]');
+      selected = i;
+      for (var j = 0; j < items.length; j++) {
+        items[j].setAttribute('aria-selected', j === i ? 'true' : 'false');
+      }
+      if (items[i]) items[i].scrollIntoView({ block: 'nearest' });
+    }
+
+    function close() {
+      list.hidden = true;
+      selected = -1;
+    }
+
+    function open(i) {
+      var hit = results[i];
+      if (!hit) return;
+      close();
+      input.blur();
+      openResult(hit.page, hit.section);
+    }
+
+    function render() {
+      var query = input.value;
+      var words = tokenize(query);
+      results = search(query);
+      list.innerHTML = '';
+      selected = -1;
+
+      if (words.length === 0) {
+        close();
+        return;
+      }
+
+      if (results.length === 0) {
+        var empty = document.createElement('li');
+        empty.className = 'mdview-search-empty';
+        empty.textContent = 'No matching pages';
+        list.appendChild(empty);
+      }
+
+      var index = getSearchIndex();
+      results.forEach(function(hit, i) {
+        var page = index.pages[hit.page];
+        var section = page.sections[hit.section];
+
+        var item = document.createElement('li');
+        item.setAttribute('role', 'option');
+        var title = document.createElement('span');
+        title.className = 'mdview-search-title';
+        var heading = section.title && section.title !== page.title ? page.title + ' \u203a ' + section.title : page.title;
+        appendMarked(title, heading, words);
+        var text = document.createElement('span');
+        text.className = 'mdview-search-snippet';
+        appendMarked(text, section.snippet, words);
+        item.appendChild(title);
+        item.appendChild(text);
+
+        // mousedown fires before the input loses focus and closes the list
+        item.addEventListener('mousedown', function(e) {
+          e.preventDefault();
+          open(i);
+        });
+        list.appendChild(item);
+      });
+      list.hidden = false;
+    }
+
+    input.addEventListener('input', render);
+    input.addEventListener('focus', function() {
+      if (input.value) render();
+    });
+    input.addEventListener('blur', close);
+    input.addEventListener('keydown', function(e) {
+      if (e.key === 'ArrowDown') {
+        e.preventDefault();
+        select(Math.min(selected + 1, results.length - 1));
+      } else if (e.key === 'ArrowUp') {
+        e.preventDefault();
+        select(Math.max(selected - 1, 0));
+      } else if (e.key === 'Enter') {
+        e.preventDefault();
+        open(selected === -1 ? 0 : selected);
+      } else if (e.key === 'Escape') {
+        input.value = '';
+        close();
+        input.blur();
+      }
+    });
+
+    // "/" focuses the search box, unless the reader is typing somewhere else
+    document.addEventListener('keydown', function(e) {
+      var tag = document.activeElement && document.activeElement.tagName;
+      if (e.key === '/' && tag !== 'INPUT' && tag !== 'TEXTAREA' && !e.ctrlKey && !e.metaKey && !e.altKey) {
+        e.preventDefault();
+        input.focus();
+      }
+    });
+  }
+
+  // Show the root page's images, which reference the asset table like every page's
+  function resolveOriginalAssets() {
+    var article = getArticle();
+    if (article && article.innerHTML.indexOf('mdview-asset:') !== -1) {
+      article.innerHTML = resolveAssets(article.innerHTML);
+    }
+  }
+
   // Initialize
   function init() {
     if (window.mdviewArchive) {
       var pageCount = Object.keys(window.mdviewArchive.pages).length;
       console.log('mdview archive loaded with', pageCount, 'pages');
     }
+    if (document.querySelector('nav.mdview-nav')) {
+      document.body.classList.add('has-page-nav');
+      highlightNav();
+    }
+    initHistory();
+    initSearch();
   }
 
+  // The script follows the article, so its images can be resolved before the page is shown
+  resolveOriginalAssets();
+
   if (document.readyState === 'loading') {
     document.addEventListener('DOMContentLoaded', init);
   } else {
@@ -1,6 +1,7 @@
 package archive
 
 import (
+	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -112,6 +113,11 @@ func (lc *linkCollector) processLink(href string) string {
 		return ""
 	}
 
+	// Percent-encoded names, e.g. "My%20Notes.md", as the converter decodes them
+	if unescaped, err := url.PathUnescape(href); err == nil {
+		href = unescaped
+	}
+
 	// Resolve relative path to absolute
 	absPath := filepath.Join(lc.baseDir, href)
 	absPath = filepath.Clean(absPath)
@@ -155,6 +161,59 @@ func deduplicateLinks(links []string) []string {
 	return result
 }
 
+// srcPattern finds src attributes in raw HTML (fallback for <img> tags)
+var srcPattern = regexp.MustCompile(`src=["']([^"']+)["']`)
+
+// ScanImageLinks extracts the absolute paths of all local images referenced by markdown content.
+// Both markdown images and <img src> in raw HTML are included; remote and data: URLs are skipped.
+func ScanImageLinks(content []byte, baseDir string) []string {
+	md := goldmark.New(goldmark.WithExtensions(extension.GFM))
+	doc := md.Parser().Parse(text.NewReader(content))
+
+	var images []string
+	ast.Walk(doc, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		if img, ok := node.(*ast.Image); ok {
+			if absPath := resolveLocalPath(string(img.Destination), baseDir); absPath != "" {
+				images = append(images, absPath)
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+
+	for _, match := range srcPattern.FindAllSubmatch(content, -1) {
+		if absPath := resolveLocalPath(string(match[1]), baseDir); absPath != "" {
+			images = append(images, absPath)
+		}
+	}
+
+	return deduplicateLinks(images)
+}
+
+// resolveLocalPath returns the absolute path of a local file reference, or "" for
+// anchors, data URIs and remote URLs
+func resolveLocalPath(href, baseDir string) string {
+	if href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(href, "data:") {
+		return ""
+	}
+
+	if strings.HasPrefix(href, "file:///") {
+		href = filepath.FromSlash(strings.TrimPrefix(href, "file:///"))
+		return filepath.Clean(href)
+	}
+
+	if strings.Contains(href, "://") {
+		return ""
+	}
+
+	href = strings.Split(href, "#")[0]
+	href = strings.Split(href, "?")[0]
+
+	return filepath.Clean(filepath.Join(baseDir, href))
+}
+
 // HasMarkdownLinks checks if a markdown file contains any links to local .md files
 func HasMarkdownLinks(mdPath string) (bool, error) {
 	content, err := os.ReadFile(mdPath)
@@ -2,6 +2,7 @@ package archive
 
 import (
 	"path/filepath"
+	"reflect"
 	"testing"
 )
 
@@ -213,3 +214,55 @@ func TestProcessLink(t *testing.T) {
 		})
 	}
 }
+
+func TestScanMarkdownLinks_FragmentsAndEscapes(t *testing.T) {
+	baseDir := t.TempDir()
+	content := []byte(`[Install](guide/setup.md#install)
+[Again](guide/setup.md?plain=1#first-steps)
+[Notes](My%20Notes.md#today)
+<a href="raw.md#section">Raw</a>
+`)
+
+	links, err := ScanMarkdownLinks(content, baseDir)
+	if err != nil {
+		t.Fatalf("ScanMarkdownLinks() error = %v", err)
+	}
+
+	want := []string{
+		filepath.Join(baseDir, "guide", "setup.md"),
+		filepath.Join(baseDir, "My Notes.md"),
+		filepath.Join(baseDir, "raw.md"),
+	}
+	if !reflect.DeepEqual(links, want) {
+		t.Errorf("ScanMarkdownLinks() = %v, want %v", links, want)
+	}
+}
+
+func TestScanImageLinks(t *testing.T) {
+	baseDir := filepath.Join("docs", "guide")
+
+	content := []byte(`# Images
+
+![logo](images/logo.png)
+![remote](https://example.com/remote.png)
+![inline](data:image/png;base64,AAAA)
+![again](images/logo.png)
+
+<img src="diagram.svg" alt="diagram">
+`)
+
+	images := ScanImageLinks(content, baseDir)
+
+	want := []string{
+		filepath.Join(baseDir, "images", "logo.png"),
+		filepath.Join(baseDir, "diagram.svg"),
+	}
+	if len(images) != len(want) {
+		t.Fatalf("ScanImageLinks() = %v, want %v", images, want)
+	}
+	for i := range want {
+		if images[i] != want[i] {
+			t.Errorf("ScanImageLinks()[%d] = %s, want %s", i, images[i], want[i])
+		}
+	}
+}
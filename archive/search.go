@@ -0,0 +1,171 @@
+package archive
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	htmlpkg "html"
+	"path"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// searchIndex is the full-text search index of an archive. It is embedded gzipped as
+// window.mdviewArchive.search and searched by navigation.js. Pages are split into
+// sections at their headings, so results can open the page at the matching heading.
+type searchIndex struct {
+	Root  string           `json:"root"`  // Key of the root page
+	Pages []searchPage     `json:"pages"` // In archive order
+	Terms map[string][]int `json:"terms"` // Term -> flat list of (page, section) index pairs
+}
+
+// searchPage is a page of the archive in the search index
+type searchPage struct {
+	Key      string          `json:"key"` // Archive key, as passed to mdviewLoadPage
+	Title    string          `json:"title"`
+	Sections []searchSection `json:"sections"`
+}
+
+// searchSection is the text under a heading, up to the next heading. Only the start of
+// the text is kept, for result snippets; the rest is found through the terms.
+type searchSection struct {
+	ID      string `json:"id"`      // Heading anchor ("" for text before the first heading)
+	Title   string `json:"title"`   // Heading text ("" for text before the first heading)
+	Snippet string `json:"snippet"` // Start of the plain text
+}
+
+const (
+	// minTermLength is the shortest indexed term in runes; shorter words are too common to be useful
+	minTermLength = 2
+	// maxSnippetLength is the longest section snippet in runes, before the ellipsis
+	maxSnippetLength = 160
+)
+
+var (
+	// Headings with an id attribute, which the converter adds to every markdown heading
+	searchHeadingPattern = regexp.MustCompile(`(?s)<h[1-6][^>]*\sid="([^"]*)"[^>]*>(.*?)</h[1-6]>`)
+	// Content that isn't page text: scripts, styles and in-page tables of contents
+	searchSkipPattern = regexp.MustCompile(`(?s)<script\b.*?</script>|<style\b.*?</style>|<nav class="toc[^"]*">.*?</nav>`)
+	// Inline tags are removed without a trace, so "<em>start</em>." stays "start."
+	searchInlineTagPattern = regexp.MustCompile(`</?(?:a|abbr|b|code|del|em|i|kbd|mark|s|small|span|strong|sub|sup)\b[^>]*>`)
+	searchTagPattern       = regexp.MustCompile(`<[^>]*>`)
+	searchSpaceRun         = regexp.MustCompile(`\s+`)
+)
+
+// newSearchIndex creates an empty search index for an archive whose root page has rootKey
+func newSearchIndex(rootKey string) *searchIndex {
+	return &searchIndex{
+		Root:  rootKey,
+		Terms: make(map[string][]int),
+	}
+}
+
+// addPage indexes a page from the HTML of its article
+func (idx *searchIndex) addPage(key, articleHTML string) {
+	articleHTML = searchSkipPattern.ReplaceAllString(articleHTML, " ")
+
+	page := searchPage{Key: key}
+	matches := searchHeadingPattern.FindAllStringSubmatchIndex(articleHTML, -1)
+
+	// Text before the first heading belongs to the page itself
+	end := len(articleHTML)
+	if len(matches) > 0 {
+		end = matches[0][0]
+	}
+	// Full section texts, for indexing; the page only keeps their snippets
+	var texts []string
+	if text := htmlToText(articleHTML[:end]); text != "" {
+		page.Sections = append(page.Sections, searchSection{Snippet: searchSnippet(text)})
+		texts = append(texts, text)
+	}
+
+	for i, m := range matches {
+		end := len(articleHTML)
+		if i+1 < len(matches) {
+			end = matches[i+1][0]
+		}
+		text := htmlToText(articleHTML[m[1]:end])
+		section := searchSection{
+			ID:      htmlpkg.UnescapeString(articleHTML[m[2]:m[3]]),
+			Title:   htmlToText(articleHTML[m[4]:m[5]]),
+			Snippet: searchSnippet(text),
+		}
+		if page.Title == "" && strings.HasPrefix(articleHTML[m[0]:], "<h1") {
+			page.Title = section.Title
+		}
+		page.Sections = append(page.Sections, section)
+		texts = append(texts, text)
+	}
+
+	// Pages without a top-level heading are titled by their file name
+	if page.Title == "" {
+		page.Title = strings.TrimSuffix(path.Base(key), path.Ext(key))
+	}
+
+	pageIndex := len(idx.Pages)
+	idx.Pages = append(idx.Pages, page)
+	for sectionIndex, section := range page.Sections {
+		seen := make(map[string]bool)
+		for _, term := range append(searchTerms(section.Title), searchTerms(texts[sectionIndex])...) {
+			if seen[term] {
+				continue
+			}
+			seen[term] = true
+			idx.Terms[term] = append(idx.Terms[term], pageIndex, sectionIndex)
+		}
+	}
+}
+
+// encode returns the index as base64-encoded gzipped JSON
+func (idx *searchIndex) encode() (string, error) {
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return "", err
+	}
+	compressed, err := compressData(data)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(compressed), nil
+}
+
+// searchTerms splits text into lowercase terms: runs of letters and digits of at least
+// minTermLength runes. navigation.js tokenizes queries the same way.
+func searchTerms(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	terms := words[:0]
+	for _, word := range words {
+		if len([]rune(word)) >= minTermLength {
+			terms = append(terms, word)
+		}
+	}
+	return terms
+}
+
+// searchSnippet returns the start of a section's text, cut after at most maxSnippetLength
+// runes at a word boundary and marked with an ellipsis when it is cut
+func searchSnippet(text string) string {
+	runes := []rune(text)
+	if len(runes) <= maxSnippetLength {
+		return text
+	}
+	cut := maxSnippetLength
+	for i := maxSnippetLength; i > maxSnippetLength/2; i-- {
+		if unicode.IsSpace(runes[i]) {
+			cut = i
+			break
+		}
+	}
+	return strings.TrimSpace(string(runes[:cut])) + "\u2026"
+}
+
+// htmlToText returns the text of an HTML fragment with tags removed and whitespace collapsed
+func htmlToText(fragment string) string {
+	text := searchInlineTagPattern.ReplaceAllString(fragment, "")
+	text = searchTagPattern.ReplaceAllString(text, " ")
+	text = htmlpkg.UnescapeString(text)
+	return strings.TrimSpace(searchSpaceRun.ReplaceAllString(text, " "))
+}
@@ -0,0 +1,155 @@
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestSearchIndex_AddPage(t *testing.T) {
+	idx := newSearchIndex("root.md")
+	idx.addPage("guide/setup.md", `<article class="markdown-body">
+<p>Before you <em>start</em>.</p>
+<nav class="toc"><ul><li><a href="#install">Install</a></li></ul></nav>
+<h1 id="setup-guide">Setup &amp; Guide</h1>
+<p>Read this first.</p>
+<h2 id="install">Install</h2>
+<p>Run <code>make install</code> &ndash; it&rsquo;s quick.</p>
+<script>var hidden = "scripted";</script>
+</article>`)
+
+	if len(idx.Pages) != 1 {
+		t.Fatalf("expected 1 page, got %d", len(idx.Pages))
+	}
+	page := idx.Pages[0]
+	if page.Key != "guide/setup.md" || page.Title != "Setup & Guide" {
+		t.Errorf("unexpected page key/title: %q, %q", page.Key, page.Title)
+	}
+
+	want := []searchSection{
+		{Snippet: "Before you start."},
+		{ID: "setup-guide", Title: "Setup & Guide", Snippet: "Read this first."},
+		{ID: "install", Title: "Install", Snippet: "Run make install – it’s quick."},
+	}
+	if !reflect.DeepEqual(page.Sections, want) {
+		t.Errorf("unexpected sections:\n got: %+v\nwant: %+v", page.Sections, want)
+	}
+
+	if got := idx.Terms["install"]; !reflect.DeepEqual(got, []int{0, 2}) {
+		t.Errorf("expected install once, in the Install section, got %v", got)
+	}
+	if got := idx.Terms["guide"]; !reflect.DeepEqual(got, []int{0, 1}) {
+		t.Errorf("expected heading text to be indexed, got %v", got)
+	}
+	for _, term := range []string{"scripted", "hidden", "a"} {
+		if _, ok := idx.Terms[term]; ok {
+			t.Errorf("expected %q not to be indexed", term)
+		}
+	}
+}
+
+func TestSearchIndex_TitleFallsBackToFileName(t *testing.T) {
+	idx := newSearchIndex("root.md")
+	idx.addPage("notes/deploy.md", `<article class="markdown-body"><h2 id="steps">Steps</h2><p>Deploy.</p></article>`)
+
+	if title := idx.Pages[0].Title; title != "deploy" {
+		t.Errorf("expected the file name as title, got %q", title)
+	}
+}
+
+func TestSearchIndex_KeepsSnippetsOnly(t *testing.T) {
+	idx := newSearchIndex("root.md")
+	body := strings.Repeat("filler words here ", 20) + "needle at the end"
+	idx.addPage("long.md", `<article class="markdown-body"><h1 id="long">Long</h1><p>`+body+`</p></article>`)
+
+	snippet := idx.Pages[0].Sections[0].Snippet
+	start := strings.TrimSuffix(snippet, "\u2026")
+	if start == snippet || !strings.HasPrefix(body, start+" ") {
+		t.Errorf("expected the start of the text cut at a word, got %q", snippet)
+	}
+	if n := len([]rune(snippet)); n > maxSnippetLength+1 {
+		t.Errorf("expected at most %d runes, got %d", maxSnippetLength+1, n)
+	}
+	if strings.Contains(snippet, "needle") {
+		t.Error("expected the end of the text to be left out of the snippet")
+	}
+	if got := idx.Terms["needle"]; !reflect.DeepEqual(got, []int{0, 0}) {
+		t.Errorf("expected words past the snippet to be indexed, got %v", got)
+	}
+}
+
+func TestSearchTerms(t *testing.T) {
+	got := searchTerms("Hello, wörld! I/O at 10:30 — naïve café_bar")
+	want := []string{"hello", "wörld", "at", "10", "30", "naïve", "café", "bar"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("searchTerms() = %v, want %v", got, want)
+	}
+}
+
+func TestArchiveConverter_EmbedsSearchIndex(t *testing.T) {
+	tempDir := t.TempDir()
+	rootPath := filepath.Join(tempDir, "root.md")
+	if err := os.WriteFile(rootPath, []byte("# Runbook\n\nSee [backups](db/backups.md).\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(tempDir, "db"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "db", "backups.md"), []byte("# Backups\n\n## Restore\n\nUse pg_restore.\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	graph, err := BuildGraph(rootPath, 10)
+	if err != nil {
+		t.Fatalf("BuildGraph() error = %v", err)
+	}
+	var buf bytes.Buffer
+	if err := NewConverter(graph, "default", true, false, "").ConvertTo(&buf); err != nil {
+		t.Fatalf("ConvertTo() error = %v", err)
+	}
+
+	match := regexp.MustCompile(`search: "([^"]+)"`).FindStringSubmatch(buf.String())
+	if match == nil {
+		t.Fatal("expected a search index in the archive")
+	}
+	compressed, err := base64.StdEncoding.DecodeString(match[1])
+	if err != nil {
+		t.Fatalf("search index is not base64: %v", err)
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		t.Fatalf("search index is not gzipped: %v", err)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var idx searchIndex
+	if err := json.Unmarshal(data, &idx); err != nil {
+		t.Fatalf("search index is not JSON: %v", err)
+	}
+	if idx.Root != "root.md" || len(idx.Pages) != 2 {
+		t.Fatalf("unexpected index root %q with %d pages", idx.Root, len(idx.Pages))
+	}
+	postings := idx.Terms["restore"]
+	if len(postings) != 2 {
+		t.Fatalf("expected one section containing restore, got %v", postings)
+	}
+	page := idx.Pages[postings[0]]
+	if page.Key != "db/backups.md" || page.Sections[postings[1]].ID != "restore" {
+		t.Errorf("expected restore in the Restore section of db/backups.md, got %s#%s", page.Key, page.Sections[postings[1]].ID)
+	}
+
+	if !bytes.Contains(buf.Bytes(), []byte(".mdview-search")) {
+		t.Error("expected the search box styles in the archive")
+	}
+}
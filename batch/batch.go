@@ -0,0 +1,397 @@
+// Package batch converts every markdown file under a directory into a mirrored tree of
+// HTML pages, e.g. to publish a docs folder as a static site. Links between the pages
+// point to the converted .html files, and local images are copied next to them.
+package batch
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"mdview/archive"
+	"mdview/converter"
+)
+
+// indexFiles name the page opened for the output tree, in order of preference
+var indexFiles = []string{"README.md", "readme.md", "index.md"}
+
+// Converter converts the markdown files of a directory tree in parallel
+type Converter struct {
+	rootDir       string
+	files         []string // Absolute paths of the markdown files to convert
+	templateName  string
+	selfContained bool
+	preload       bool
+	tocSidebar    bool
+	tocDepth      int
+	highlight     bool
+	extensions    converter.Extensions
+	workers       int
+
+	copiedMu sync.Mutex
+	copied   map[string]bool // Assets already copied to the output tree by the current Convert
+}
+
+// IsPattern reports whether input is a glob pattern rather than a path
+func IsPattern(input string) bool {
+	return strings.ContainsAny(input, "*?[")
+}
+
+// IsBatchInput reports whether input selects a batch conversion: a directory or a glob pattern
+func IsBatchInput(input string) bool {
+	if IsPattern(input) {
+		return true
+	}
+	info, err := os.Stat(input)
+	return err == nil && info.IsDir()
+}
+
+// New creates a Converter for input: a directory, whose markdown files are converted
+// recursively, or a glob pattern (see filepath.Match) matching markdown files and
+// directories. The output tree mirrors the directory, or the deepest directory
+// containing every match of the pattern.
+func New(input, templateName string) (*Converter, error) {
+	rootDir, files, err := collect(input)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no markdown files found in %s", input)
+	}
+
+	return &Converter{
+		rootDir:      rootDir,
+		files:        files,
+		templateName: templateName,
+		extensions:   converter.DefaultExtensions(),
+		workers:      runtime.NumCPU(),
+	}, nil
+}
+
+// SetSelfContained embeds images as base64 data URIs instead of copying them to the output tree
+func (c *Converter) SetSelfContained(enabled bool) {
+	c.selfContained = enabled
+}
+
+// SetPreload enables preloading all images in a directory when the first one is embedded
+func (c *Converter) SetPreload(enabled bool) {
+	c.preload = enabled
+}
+
+// SetTOCSidebar enables the table of contents sidebar on every page
+func (c *Converter) SetTOCSidebar(enabled bool) {
+	c.tocSidebar = enabled
+}
+
+// SetTOCDepth sets the deepest heading level included in tables of contents
+func (c *Converter) SetTOCDepth(depth int) {
+	c.tocDepth = depth
+}
+
+// SetHighlight enables conversion-time syntax highlighting
+func (c *Converter) SetHighlight(enabled bool) {
+	c.highlight = enabled
+}
+
+// SetExtensions selects the markdown extensions used for every page
+func (c *Converter) SetExtensions(ext converter.Extensions) {
+	c.extensions = ext
+}
+
+// SetWorkers sets the number of files converted in parallel (values below 1 mean one per CPU)
+func (c *Converter) SetWorkers(n int) {
+	if n < 1 {
+		n = runtime.NumCPU()
+	}
+	c.workers = n
+}
+
+// RootDir returns the absolute path of the directory the output tree mirrors
+func (c *Converter) RootDir() string {
+	return c.rootDir
+}
+
+// Files returns the absolute paths of the markdown files to convert, sorted
+func (c *Converter) Files() []string {
+	return append([]string(nil), c.files...)
+}
+
+// IndexFile returns the README or index markdown file at the top of the tree, or "" if there is none
+func (c *Converter) IndexFile() string {
+	for _, name := range indexFiles {
+		indexPath := filepath.Join(c.rootDir, name)
+		for _, file := range c.files {
+			if file == indexPath {
+				return file
+			}
+		}
+	}
+	return ""
+}
+
+// OutputPath returns where the page for the markdown file mdPath is written in outputDir
+func (c *Converter) OutputPath(outputDir, mdPath string) string {
+	relPath, err := filepath.Rel(c.rootDir, mdPath)
+	if err != nil {
+		relPath = filepath.Base(mdPath)
+	}
+	return filepath.Join(outputDir, strings.TrimSuffix(relPath, filepath.Ext(relPath))+".html")
+}
+
+// Convert converts every markdown file into outputDir and returns the paths of the
+// generated pages. A file that fails to convert doesn't stop the others; the returned
+// error joins the failures.
+func (c *Converter) Convert(outputDir string) ([]string, error) {
+	absOutput, err := filepath.Abs(outputDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve output directory: %w", err)
+	}
+
+	c.copiedMu.Lock()
+	c.copied = make(map[string]bool)
+	c.copiedMu.Unlock()
+
+	// Workers receive indexes into c.files and store their result at the same index
+	jobs := make(chan int)
+	results := make([]string, len(c.files))
+	errs := make([]error, len(c.files))
+
+	workers := min(c.workers, len(c.files))
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i], errs[i] = c.convertFile(absOutput, c.files[i])
+			}
+		}()
+	}
+
+	for i := range c.files {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	var pages []string
+	for _, page := range results {
+		if page != "" {
+			pages = append(pages, page)
+		}
+	}
+	return pages, errors.Join(errs...)
+}
+
+// convertFile converts one markdown file into the output tree and copies its images
+func (c *Converter) convertFile(outputDir, mdPath string) (string, error) {
+	content, err := os.ReadFile(mdPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", mdPath, err)
+	}
+
+	conv := converter.New()
+	conv.SetBaseDir(filepath.Dir(mdPath))
+	conv.SetSiteRoot(c.rootDir)
+	conv.SetSourcePath(mdPath)
+	conv.SetTitle(strings.TrimSuffix(filepath.Base(mdPath), filepath.Ext(mdPath)))
+	conv.SetSelfContained(c.selfContained)
+	conv.SetPreload(c.preload)
+	conv.SetTOCSidebar(c.tocSidebar)
+	conv.SetTOCDepth(c.tocDepth)
+	conv.SetHighlight(c.highlight)
+	conv.SetExtensions(c.extensions)
+
+	var buf bytes.Buffer
+	if err := conv.ConvertWithSize(bytes.NewReader(content), &buf, c.templateName, int64(len(content))); err != nil {
+		return "", fmt.Errorf("failed to convert %s: %w", mdPath, err)
+	}
+
+	outputPath := c.OutputPath(outputDir, mdPath)
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		return "", fmt.Errorf("failed to create output directory: %w", err)
+	}
+	if err := os.WriteFile(outputPath, buf.Bytes(), 0644); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", outputPath, err)
+	}
+
+	// Embedded images don't need a copy
+	if !c.selfContained {
+		for _, image := range archive.ScanImageLinks(content, filepath.Dir(mdPath)) {
+			if err := c.copyAsset(outputDir, image); err != nil {
+				return outputPath, err
+			}
+		}
+	}
+
+	return outputPath, nil
+}
+
+// copyAsset copies a local file under the root directory to the same place in the output
+// tree, once per conversion. Files outside the root or missing ones are skipped: the
+// page links them with a file:// URL or the link is already broken. So is a file that
+// already is its own copy, when the output tree is the root directory itself.
+func (c *Converter) copyAsset(outputDir, assetPath string) error {
+	relPath, err := filepath.Rel(c.rootDir, assetPath)
+	if err != nil || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		return nil
+	}
+
+	c.copiedMu.Lock()
+	if c.copied[assetPath] {
+		c.copiedMu.Unlock()
+		return nil
+	}
+	c.copied[assetPath] = true
+	c.copiedMu.Unlock()
+
+	src, err := os.Open(assetPath)
+	if err != nil {
+		return nil
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil || !info.Mode().IsRegular() {
+		return nil
+	}
+
+	dstPath := filepath.Join(outputDir, relPath)
+	// Creating the copy would truncate the source
+	if dstInfo, err := os.Stat(dstPath); err == nil && os.SameFile(info, dstInfo) {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(dstPath), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	dst, err := os.Create(dstPath)
+	if err != nil {
+		return fmt.Errorf("failed to copy %s: %w", assetPath, err)
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return fmt.Errorf("failed to copy %s: %w", assetPath, err)
+	}
+	return dst.Close()
+}
+
+// collect returns the root directory of the output tree and the sorted markdown files for input
+func collect(input string) (string, []string, error) {
+	if !IsPattern(input) {
+		rootDir, err := filepath.Abs(input)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to resolve input directory: %w", err)
+		}
+		info, err := os.Stat(rootDir)
+		if err != nil {
+			return "", nil, fmt.Errorf("input directory does not exist: %s", input)
+		}
+		if !info.IsDir() {
+			return "", nil, fmt.Errorf("input is not a directory: %s", input)
+		}
+		files, err := walkMarkdown(rootDir)
+		if err != nil {
+			return "", nil, err
+		}
+		return rootDir, files, nil
+	}
+
+	matches, err := filepath.Glob(input)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid pattern %s: %w", input, err)
+	}
+
+	var rootDir string
+	seen := make(map[string]bool)
+	var files []string
+	for _, match := range matches {
+		// Hidden files and directories are skipped, as when walking a directory
+		if strings.HasPrefix(filepath.Base(match), ".") {
+			continue
+		}
+		absPath, err := filepath.Abs(match)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to resolve %s: %w", match, err)
+		}
+		info, err := os.Stat(absPath)
+		if err != nil {
+			continue
+		}
+
+		var found []string
+		dir := filepath.Dir(absPath)
+		if info.IsDir() {
+			dir = absPath
+			if found, err = walkMarkdown(absPath); err != nil {
+				return "", nil, err
+			}
+		} else if isMarkdown(absPath) {
+			found = []string{absPath}
+		} else {
+			continue
+		}
+
+		rootDir = commonDir(rootDir, dir)
+		for _, file := range found {
+			if !seen[file] {
+				seen[file] = true
+				files = append(files, file)
+			}
+		}
+	}
+
+	sort.Strings(files)
+	return rootDir, files, nil
+}
+
+// walkMarkdown returns the markdown files under dir, skipping hidden files and directories
+func walkMarkdown(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != dir && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() && isMarkdown(path) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %w", dir, err)
+	}
+	return files, nil
+}
+
+// isMarkdown reports whether path has a .md extension
+func isMarkdown(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".md")
+}
+
+// commonDir returns the deepest directory containing both a and b ("" counts as unset)
+func commonDir(a, b string) string {
+	if a == "" {
+		return b
+	}
+	for {
+		if rel, err := filepath.Rel(a, b); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return a
+		}
+		parent := filepath.Dir(a)
+		if parent == a {
+			return a
+		}
+		a = parent
+	}
+}
@@ -0,0 +1,255 @@
+package batch
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTree creates the given files (slash-separated paths relative to dir)
+func writeTree(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", path, err)
+	}
+	return string(data)
+}
+
+func TestConvert_MirrorsTree(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"docs/README.md":            "# Home\n\n[Setup](guide/setup.md#install)\n\n![logo](img/logo.png)\n",
+		"docs/guide/setup.md":       "# Setup\n\n[Home](../README.md)\n\n![shot](shot.png)\n",
+		"docs/guide/shot.png":       "png",
+		"docs/img/logo.png":         "png",
+		"docs/img/unused.png":       "png",
+		"docs/notes.txt":            "not markdown",
+		"docs/.drafts/secret.md":    "# Draft\n",
+		"docs/node/.hidden-file.md": "# Hidden\n",
+	})
+	input := filepath.Join(dir, "docs")
+	outputDir := filepath.Join(dir, "site")
+
+	bc, err := New(input, "default")
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	bc.SetWorkers(2)
+	pages, err := bc.Convert(outputDir)
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	want := []string{
+		filepath.Join(outputDir, "README.html"),
+		filepath.Join(outputDir, "guide", "setup.html"),
+	}
+	if len(pages) != len(want) || pages[0] != want[0] || pages[1] != want[1] {
+		t.Errorf("expected pages %v, got %v", want, pages)
+	}
+
+	home := readFile(t, want[0])
+	if !strings.Contains(home, `href="guide/setup.html#install"`) {
+		t.Error("expected the link to point to the converted page")
+	}
+	if !strings.Contains(home, `src="img/logo.png"`) {
+		t.Error("expected the image to stay relative")
+	}
+	if !strings.Contains(readFile(t, want[1]), `href="../README.html"`) {
+		t.Error("expected the link back to point to the converted page")
+	}
+
+	for _, name := range []string{"img/logo.png", "guide/shot.png"} {
+		if _, err := os.Stat(filepath.Join(outputDir, filepath.FromSlash(name))); err != nil {
+			t.Errorf("expected %s to be copied: %v", name, err)
+		}
+	}
+	for _, name := range []string{"img/unused.png", "notes.txt", ".drafts/secret.html", "node/.hidden-file.html"} {
+		if _, err := os.Stat(filepath.Join(outputDir, filepath.FromSlash(name))); !os.IsNotExist(err) {
+			t.Errorf("expected %s not to be written", name)
+		}
+	}
+}
+
+func TestConvert_SelfContainedDoesNotCopyImages(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"docs/index.md": "![logo](logo.png)\n",
+		"docs/logo.png": "png",
+	})
+	outputDir := filepath.Join(dir, "site")
+
+	bc, err := New(filepath.Join(dir, "docs"), "default")
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	bc.SetSelfContained(true)
+	if _, err := bc.Convert(outputDir); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	if !strings.Contains(readFile(t, filepath.Join(outputDir, "index.html")), `src="data:image/png;base64,`) {
+		t.Error("expected the image to be embedded")
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "logo.png")); !os.IsNotExist(err) {
+		t.Error("expected the embedded image not to be copied")
+	}
+}
+
+func TestConvert_IntoInputDirectory(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"docs/index.md":     "![logo](img/logo.png)\n",
+		"docs/img/logo.png": "png bytes",
+	})
+	docs := filepath.Join(dir, "docs")
+
+	bc, err := New(docs, "default")
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if _, err := bc.Convert(docs); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	if got := readFile(t, filepath.Join(docs, "img", "logo.png")); got != "png bytes" {
+		t.Errorf("expected the image to be left unchanged, got %q", got)
+	}
+	if !strings.Contains(readFile(t, filepath.Join(docs, "index.html")), `src="img/logo.png"`) {
+		t.Error("expected the page next to its markdown file")
+	}
+}
+
+func TestConvert_ReportsFailuresAndConvertsTheRest(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"docs/a.md": "# A\n",
+		"docs/b.md": "# B\n",
+	})
+	outputDir := filepath.Join(dir, "site")
+
+	bc, err := New(filepath.Join(dir, "docs"), "default")
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	// A directory where the page should go makes writing it fail
+	if err := os.MkdirAll(filepath.Join(outputDir, "a.html"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	pages, err := bc.Convert(outputDir)
+	if err == nil || !strings.Contains(err.Error(), "a.html") {
+		t.Errorf("expected an error for a.html, got: %v", err)
+	}
+	if len(pages) != 1 || pages[0] != filepath.Join(outputDir, "b.html") {
+		t.Errorf("expected b.html to be converted, got %v", pages)
+	}
+}
+
+func TestNew_Pattern(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"docs/api/a.md":    "# A\n",
+		"docs/guide/b.md":  "# B\n",
+		"docs/guide/c.txt": "C\n",
+		"docs/top.md":      "# Top\n",
+	})
+
+	bc, err := New(filepath.Join(dir, "docs", "*", "*"), "default")
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	if bc.RootDir() != filepath.Join(dir, "docs") {
+		t.Errorf("expected root to be the common directory, got %s", bc.RootDir())
+	}
+	want := []string{
+		filepath.Join(dir, "docs", "api", "a.md"),
+		filepath.Join(dir, "docs", "guide", "b.md"),
+	}
+	files := bc.Files()
+	if len(files) != len(want) || files[0] != want[0] || files[1] != want[1] {
+		t.Errorf("expected files %v, got %v", want, files)
+	}
+	if got := bc.OutputPath("/out", want[1]); got != filepath.Join("/out", "guide", "b.html") {
+		t.Errorf("unexpected output path %s", got)
+	}
+}
+
+func TestNew_Errors(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"empty/notes.txt": "text\n"})
+
+	inputs := []string{
+		filepath.Join(dir, "missing"),
+		filepath.Join(dir, "empty"),
+		filepath.Join(dir, "*.md"),
+		filepath.Join(dir, "[bad"),
+	}
+	for _, input := range inputs {
+		if _, err := New(input, "default"); err == nil {
+			t.Errorf("expected an error for %s", input)
+		}
+	}
+}
+
+func TestIndexFile(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"docs/index.md":        "# Index\n",
+		"docs/sub/README.md":   "# Sub\n",
+		"nodocs/guide/a.md":    "# A\n",
+		"nodocs/guide/more.md": "# More\n",
+	})
+
+	bc, err := New(filepath.Join(dir, "docs"), "default")
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if got := bc.IndexFile(); got != filepath.Join(dir, "docs", "index.md") {
+		t.Errorf("expected the top-level index.md, got %q", got)
+	}
+
+	bc, err = New(filepath.Join(dir, "nodocs"), "default")
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if got := bc.IndexFile(); got != "" {
+		t.Errorf("expected no index file, got %q", got)
+	}
+}
+
+func TestIsBatchInput(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"a.md": "# A\n"})
+
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{dir, true},
+		{filepath.Join(dir, "*.md"), true},
+		{filepath.Join(dir, "a.md"), false},
+		{filepath.Join(dir, "missing"), false},
+	}
+	for _, tt := range tests {
+		if got := IsBatchInput(tt.input); got != tt.want {
+			t.Errorf("IsBatchInput(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
@@ -10,6 +10,12 @@ import (
 // Open opens the specified file path in the default web browser.
 // The path should be an absolute file path.
 func Open(filePath string) error {
+	return OpenWith("", filePath)
+}
+
+// OpenWith opens the specified file path with the given browser executable.
+// If executable is empty, the default web browser is used.
+func OpenWith(executable, filePath string) error {
 	// Convert to absolute path if not already
 	absPath, err := filepath.Abs(filePath)
 	if err != nil {
@@ -20,15 +26,30 @@ func Open(filePath string) error {
 	// On Windows, we need to handle the path format properly
 	url := pathToFileURL(absPath)
 
-	// On Windows, use cmd /c start to open the default browser
-	// The empty string argument after "start" is the window title
-	// This prevents issues with paths containing spaces
-	cmd := exec.Command("cmd", "/c", "start", "", url)
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to open browser: %w", err)
+	return OpenURLWith(executable, url)
+}
+
+// OpenURL opens the specified URL (http://, file://, ...) in the default web browser.
+func OpenURL(url string) error {
+	return OpenURLWith("", url)
+}
+
+// OpenURLWith opens the specified URL with the given browser executable, which may be
+// a path or a name found on the PATH. If executable is empty, the default web browser is used.
+func OpenURLWith(executable, url string) error {
+	if executable == "" {
+		return openDefault(url)
 	}
+	return openWith(executable, url)
+}
 
-	return nil
+// startDetached starts a browser without waiting for it, since a browser started
+// directly (rather than through a launcher) runs until the user closes it
+func startDetached(cmd *exec.Cmd) error {
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to open browser: %w", err)
+	}
+	return cmd.Process.Release()
 }
 
 // pathToFileURL converts a file path to a file:// URL
@@ -0,0 +1,29 @@
+package browser
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// openDefault opens url in the default browser
+func openDefault(url string) error {
+	if err := exec.Command("open", url).Run(); err != nil {
+		return fmt.Errorf("failed to open browser: %w", err)
+	}
+
+	return nil
+}
+
+// openWith opens url with a browser executable. Names that aren't on the PATH
+// are treated as application names, e.g. "Firefox" or "Google Chrome".
+func openWith(executable, url string) error {
+	if path, err := exec.LookPath(executable); err == nil {
+		return startDetached(exec.Command(path, url))
+	}
+
+	if err := exec.Command("open", "-a", executable, url).Run(); err != nil {
+		return fmt.Errorf("failed to open browser %s: %w", executable, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+//go:build !windows && !darwin
+
+package browser
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// openDefault opens url with the first browser in $BROWSER that can be started,
+// falling back to xdg-open
+func openDefault(url string) error {
+	for _, command := range browserEnvCommands(os.Getenv("BROWSER"), url) {
+		path, err := exec.LookPath(command[0])
+		if err != nil {
+			continue
+		}
+		if err := startDetached(exec.Command(path, command[1:]...)); err == nil {
+			return nil
+		}
+	}
+
+	path, err := exec.LookPath("xdg-open")
+	if err != nil {
+		return errors.New("failed to open browser: xdg-open not found (set $BROWSER or use --browser)")
+	}
+	if err := exec.Command(path, url).Run(); err != nil {
+		return fmt.Errorf("failed to open browser: %w", err)
+	}
+
+	return nil
+}
+
+// openWith opens url with a browser executable
+func openWith(executable, url string) error {
+	path, err := exec.LookPath(executable)
+	if err != nil {
+		return fmt.Errorf("failed to open browser: %w", err)
+	}
+	return startDetached(exec.Command(path, url))
+}
+
+// browserEnvCommands parses a $BROWSER value: a colon-separated list of commands to
+// try in order. "%s" in a command is replaced by the URL (and "%%" by "%");
+// commands without "%s" get the URL as their last argument.
+func browserEnvCommands(env, url string) [][]string {
+	var commands [][]string
+	for _, entry := range strings.Split(env, ":") {
+		fields := strings.Fields(entry)
+		if len(fields) == 0 {
+			continue
+		}
+
+		hasURL := false
+		for i, field := range fields {
+			if strings.Contains(field, "%s") {
+				hasURL = true
+			}
+			field = strings.ReplaceAll(field, "%s", url)
+			fields[i] = strings.ReplaceAll(field, "%%", "%")
+		}
+		if !hasURL {
+			fields = append(fields, url)
+		}
+		commands = append(commands, fields)
+	}
+	return commands
+}
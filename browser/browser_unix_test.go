@@ -0,0 +1,64 @@
+//go:build !windows && !darwin
+
+package browser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBrowserEnvCommands(t *testing.T) {
+	const url = "file:///tmp/doc.html"
+
+	tests := []struct {
+		name string
+		env  string
+		want [][]string
+	}{
+		{
+			name: "empty",
+			env:  "",
+			want: nil,
+		},
+		{
+			name: "single command",
+			env:  "firefox",
+			want: [][]string{{"firefox", url}},
+		},
+		{
+			name: "command with arguments",
+			env:  "chromium --new-window",
+			want: [][]string{{"chromium", "--new-window", url}},
+		},
+		{
+			name: "placeholder",
+			env:  "open-it --url=%s --done",
+			want: [][]string{{"open-it", "--url=" + url, "--done"}},
+		},
+		{
+			name: "escaped percent",
+			env:  "tool 100%% %s",
+			want: [][]string{{"tool", "100%", url}},
+		},
+		{
+			name: "fallback list",
+			env:  "w3m: :lynx",
+			want: [][]string{{"w3m", url}, {"lynx", url}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := browserEnvCommands(tt.env, url)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("browserEnvCommands(%q) = %q, want %q", tt.env, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOpenWith_MissingExecutable(t *testing.T) {
+	if err := OpenURLWith("mdview-no-such-browser", "http://localhost/"); err == nil {
+		t.Error("expected error for a browser that isn't installed")
+	}
+}
@@ -0,0 +1,34 @@
+package browser
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// openDefault opens url in the default browser
+func openDefault(url string) error {
+	// On Windows, use cmd /c start to open the default browser
+	// The empty string argument after "start" is the window title
+	// This prevents issues with paths containing spaces
+	cmd := exec.Command("cmd", "/c", "start", "", url)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to open browser: %w", err)
+	}
+
+	return nil
+}
+
+// openWith opens url with a browser executable. Names that aren't on the PATH,
+// such as "chrome" or "msedge", are resolved by start through the App Paths registry.
+func openWith(executable, url string) error {
+	if path, err := exec.LookPath(executable); err == nil {
+		return startDetached(exec.Command(path, url))
+	}
+
+	cmd := exec.Command("cmd", "/c", "start", "", executable, url)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to open browser %s: %w", executable, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,280 @@
+// Package config loads .mdview.yaml configuration files, which set defaults for the
+// command-line flags. A project config is found by walking up from the input, and a
+// user config in the user config directory applies everywhere; the project config
+// takes precedence over the user config, and flags take precedence over both. Settings
+// that run code, browser and template-dir, are only accepted from the user config.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileName is the name of a project config file
+const FileName = ".mdview.yaml"
+
+// Config holds the settings of a config file. Nil fields are not set by the file.
+type Config struct {
+	Template      *string    `yaml:"template"`
+	TemplateDir   *string    `yaml:"template-dir"` // Resolved against the config file's directory; user config only
+	SelfContained *bool      `yaml:"self-contained"`
+	Preload       *bool      `yaml:"preload"`
+	MaxPages      *int       `yaml:"max-pages"`
+	Nav           *string    `yaml:"nav"`
+	TOC           *bool      `yaml:"toc"`
+	TOCDepth      *int       `yaml:"toc-depth"`
+	Highlight     *bool      `yaml:"highlight"`
+	Browser       *string    `yaml:"browser"` // User config only
+	Extensions    Extensions `yaml:"extensions"`
+	Output        string     `yaml:"output"` // Directory for generated files, resolved against the config file's directory
+	Files         []string   `yaml:"-"`      // Paths of the loaded config files, in order of precedence (lowest first)
+}
+
+// Extensions is a comma-separated extension list in the syntax of the --extensions flag.
+// In YAML it is either such a string or a sequence of entries.
+type Extensions string
+
+// UnmarshalYAML implements yaml.Unmarshaler
+func (e *Extensions) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.SequenceNode {
+		var entries []string
+		if err := node.Decode(&entries); err != nil {
+			return err
+		}
+		*e = Extensions(strings.Join(entries, ","))
+		return nil
+	}
+
+	var spec string
+	if err := node.Decode(&spec); err != nil {
+		return err
+	}
+	*e = Extensions(spec)
+	return nil
+}
+
+// UserPath returns the path of the user config file, e.g. ~/.config/mdview/config.yaml
+// on Linux or %AppData%\mdview\config.yaml on Windows, or "" if there is no user config directory
+func UserPath() string {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(configDir, "mdview", "config.yaml")
+}
+
+// Find returns the path of the nearest .mdview.yaml in startDir or one of its parents,
+// or "" if there is none
+func Find(startDir string) string {
+	dir, err := filepath.Abs(startDir)
+	if err != nil {
+		return ""
+	}
+	for {
+		path := filepath.Join(dir, FileName)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// Load reads the user config and the nearest project config for startDir and merges
+// them. Missing files are skipped; an empty Config is returned when there are none.
+// A project config comes with the documents, so one that sets a user-only key, or an
+// output outside its own directory, is an error: mdview writes and removes files there.
+func Load(startDir string) (*Config, error) {
+	cfg := &Config{}
+	userPath := UserPath()
+	for _, path := range []string{userPath, Find(startDir)} {
+		if path == "" {
+			continue
+		}
+		file, err := LoadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if path != userPath {
+			if keys := file.userOnlyKeys(); len(keys) > 0 {
+				return nil, fmt.Errorf("%s can only be set in the user config %s, not in %s", strings.Join(keys, " and "), userPath, path)
+			}
+			if file.Output != "" && !isSubdir(filepath.Dir(path), file.Output) {
+				return nil, fmt.Errorf("output in %s must be a directory below %s, not %s", path, filepath.Dir(path), file.Output)
+			}
+		}
+		cfg.Merge(file)
+	}
+	return cfg, nil
+}
+
+// userOnlyKeys returns the keys c sets that only the user config may set: the command
+// that opens the output, and the templates, whose scripts go into every page
+func (c *Config) userOnlyKeys() []string {
+	var keys []string
+	if c.TemplateDir != nil {
+		keys = append(keys, "template-dir")
+	}
+	if c.Browser != nil {
+		keys = append(keys, "browser")
+	}
+	return keys
+}
+
+// isSubdir reports whether path is a directory below dir. Both are absolute and clean.
+func isSubdir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
+
+// LoadFile reads a single config file. Unknown keys are an error, to catch typos.
+func LoadFile(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &Config{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+
+	dir := filepath.Dir(path)
+	if cfg.TemplateDir != nil {
+		resolved := resolvePath(dir, *cfg.TemplateDir)
+		cfg.TemplateDir = &resolved
+	}
+	cfg.Output = resolvePath(dir, cfg.Output)
+	cfg.Files = []string{path}
+	return cfg, nil
+}
+
+// resolvePath expands a leading ~ and makes a relative path relative to dir
+func resolvePath(dir, path string) string {
+	if path == "" {
+		return ""
+	}
+	if path == "~" || strings.HasPrefix(path, "~/") || strings.HasPrefix(path, `~\`) {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[1:])
+		}
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	return filepath.Clean(path)
+}
+
+// Merge applies the settings of other on top of c. Extension lists are concatenated,
+// so other's entries apply after c's.
+func (c *Config) Merge(other *Config) {
+	if other.Template != nil {
+		c.Template = other.Template
+	}
+	if other.TemplateDir != nil {
+		c.TemplateDir = other.TemplateDir
+	}
+	if other.SelfContained != nil {
+		c.SelfContained = other.SelfContained
+	}
+	if other.Preload != nil {
+		c.Preload = other.Preload
+	}
+	if other.MaxPages != nil {
+		c.MaxPages = other.MaxPages
+	}
+	if other.Nav != nil {
+		c.Nav = other.Nav
+	}
+	if other.TOC != nil {
+		c.TOC = other.TOC
+	}
+	if other.TOCDepth != nil {
+		c.TOCDepth = other.TOCDepth
+	}
+	if other.Highlight != nil {
+		c.Highlight = other.Highlight
+	}
+	if other.Browser != nil {
+		c.Browser = other.Browser
+	}
+	if other.Extensions != "" {
+		if c.Extensions != "" {
+			c.Extensions += ","
+		}
+		c.Extensions += other.Extensions
+	}
+	if other.Output != "" {
+		c.Output = other.Output
+	}
+	c.Files = append(c.Files, other.Files...)
+}
+
+// Apply sets the flags of fs that the config sets and the command line didn't, so flags
+// override the config. Flags fs doesn't define are skipped. Extensions and Output have
+// no flag to set and are left to the caller.
+func (c *Config) Apply(fs *flag.FlagSet) error {
+	values := map[string]string{}
+	if c.Template != nil {
+		values["template"] = *c.Template
+	}
+	if c.TemplateDir != nil {
+		values["template-dir"] = *c.TemplateDir
+	}
+	if c.SelfContained != nil {
+		values["self-contained"] = strconv.FormatBool(*c.SelfContained)
+	}
+	if c.Preload != nil {
+		values["preload"] = strconv.FormatBool(*c.Preload)
+	}
+	if c.MaxPages != nil {
+		values["max-pages"] = strconv.Itoa(*c.MaxPages)
+	}
+	if c.Nav != nil {
+		values["nav"] = *c.Nav
+	}
+	if c.TOC != nil {
+		values["toc"] = strconv.FormatBool(*c.TOC)
+	}
+	if c.TOCDepth != nil {
+		values["toc-depth"] = strconv.Itoa(*c.TOCDepth)
+	}
+	if c.Highlight != nil {
+		values["highlight"] = strconv.FormatBool(*c.Highlight)
+	}
+	if c.Browser != nil {
+		values["browser"] = *c.Browser
+	}
+
+	setOnCommandLine := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		setOnCommandLine[f.Name] = true
+	})
+
+	for name, value := range values {
+		if setOnCommandLine[name] || fs.Lookup(name) == nil {
+			continue
+		}
+		if err := fs.Set(name, value); err != nil {
+			return fmt.Errorf("invalid %s in config: %w", name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setupUserConfig points the user config directory at a temporary directory and
+// returns the user config path
+func setupUserConfig(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	t.Setenv("AppData", filepath.Join(home, "AppData"))
+	return UserPath()
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFind_WalksUp(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "repo", FileName), "toc: true\n")
+	nested := filepath.Join(dir, "repo", "docs", "guide")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := Find(nested); got != filepath.Join(dir, "repo", FileName) {
+		t.Errorf("expected the config in the repo root, got %q", got)
+	}
+	if got := Find(dir); got != "" {
+		t.Errorf("expected no config, got %q", got)
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, FileName)
+	writeFile(t, path, `template: paper
+template-dir: branding
+self-contained: true
+max-pages: 25
+nav: links
+extensions: [footnotes, -hardwraps]
+output: ../site
+`)
+
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile failed: %v", err)
+	}
+
+	if cfg.Template == nil || *cfg.Template != "paper" {
+		t.Errorf("unexpected template %v", cfg.Template)
+	}
+	if cfg.TemplateDir == nil || *cfg.TemplateDir != filepath.Join(dir, "branding") {
+		t.Errorf("expected template-dir relative to the config file, got %v", cfg.TemplateDir)
+	}
+	if cfg.SelfContained == nil || !*cfg.SelfContained {
+		t.Error("expected self-contained to be set")
+	}
+	if cfg.MaxPages == nil || *cfg.MaxPages != 25 {
+		t.Errorf("unexpected max-pages %v", cfg.MaxPages)
+	}
+	if cfg.Nav == nil || *cfg.Nav != "links" {
+		t.Errorf("unexpected nav %v", cfg.Nav)
+	}
+	if cfg.Preload != nil || cfg.TOC != nil {
+		t.Error("expected settings missing from the file to be nil")
+	}
+	if cfg.Extensions != "footnotes,-hardwraps" {
+		t.Errorf("expected the extension list to be joined, got %q", cfg.Extensions)
+	}
+	if cfg.Output != filepath.Join(filepath.Dir(dir), "site") {
+		t.Errorf("expected output relative to the config file, got %q", cfg.Output)
+	}
+}
+
+func TestLoadFile_Errors(t *testing.T) {
+	dir := t.TempDir()
+	tests := map[string]string{
+		"unknown key": "templte: paper\n",
+		"wrong type":  "max-pages: many\n",
+		"invalid":     "template: [\n",
+	}
+	for name, content := range tests {
+		path := filepath.Join(dir, strings.ReplaceAll(name, " ", "-")+".yaml")
+		writeFile(t, path, content)
+		if _, err := LoadFile(path); err == nil || !strings.Contains(err.Error(), path) {
+			t.Errorf("%s: expected an error naming the file, got: %v", name, err)
+		}
+	}
+}
+
+func TestLoadFile_Empty(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	writeFile(t, path, "# Nothing set yet\n")
+
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile failed: %v", err)
+	}
+	if cfg.Template != nil || cfg.Output != "" {
+		t.Error("expected an empty config")
+	}
+}
+
+func TestLoad_ProjectOverridesUser(t *testing.T) {
+	userPath := setupUserConfig(t)
+	writeFile(t, userPath, "template: dark\ntoc: true\nextensions: footnotes\n")
+	project := t.TempDir()
+	writeFile(t, filepath.Join(project, FileName), "template: paper\nextensions: -typographer\n")
+
+	cfg, err := Load(project)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if *cfg.Template != "paper" {
+		t.Errorf("expected the project template, got %q", *cfg.Template)
+	}
+	if cfg.TOC == nil || !*cfg.TOC {
+		t.Error("expected toc from the user config")
+	}
+	if cfg.Extensions != "footnotes,-typographer" {
+		t.Errorf("expected the project extensions after the user ones, got %q", cfg.Extensions)
+	}
+	if len(cfg.Files) != 2 || cfg.Files[0] != userPath {
+		t.Errorf("unexpected files %v", cfg.Files)
+	}
+}
+
+func TestLoad_UserOnlyKeys(t *testing.T) {
+	userPath := setupUserConfig(t)
+	writeFile(t, userPath, "browser: firefox\ntemplate-dir: ~/templates\n")
+	project := t.TempDir()
+
+	cfg, err := Load(project)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Browser == nil || *cfg.Browser != "firefox" || cfg.TemplateDir == nil {
+		t.Error("expected browser and template-dir from the user config")
+	}
+
+	for _, content := range []string{"browser: evil %s\n", "template-dir: scripts\n"} {
+		projectPath := filepath.Join(project, FileName)
+		writeFile(t, projectPath, "toc: true\n"+content)
+		key := strings.SplitN(content, ":", 2)[0]
+		if _, err := Load(project); err == nil || !strings.Contains(err.Error(), key) || !strings.Contains(err.Error(), projectPath) {
+			t.Errorf("expected an error for %s in a project config, got: %v", key, err)
+		}
+	}
+}
+
+func TestLoad_ProjectOutputStaysInProject(t *testing.T) {
+	userPath := setupUserConfig(t)
+	elsewhere := t.TempDir()
+	writeFile(t, userPath, "output: "+elsewhere+"\n")
+	project := t.TempDir()
+	projectPath := filepath.Join(project, FileName)
+
+	cfg, err := Load(project)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Output != elsewhere {
+		t.Errorf("expected any output from the user config, got %q", cfg.Output)
+	}
+
+	writeFile(t, projectPath, "output: build/docs\n")
+	cfg, err = Load(project)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if want := filepath.Join(project, "build", "docs"); cfg.Output != want {
+		t.Errorf("expected output %q, got %q", want, cfg.Output)
+	}
+
+	for _, output := range []string{elsewhere, ".", "..", "../sibling", "build/../..", "~/docs"} {
+		writeFile(t, projectPath, "output: "+output+"\n")
+		if _, err := Load(project); err == nil || !strings.Contains(err.Error(), "output") || !strings.Contains(err.Error(), projectPath) {
+			t.Errorf("expected an error for output %q in a project config, got: %v", output, err)
+		}
+	}
+}
+
+func TestLoad_NoFiles(t *testing.T) {
+	setupUserConfig(t)
+
+	cfg, err := Load(t.TempDir())
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(cfg.Files) != 0 {
+		t.Errorf("expected no config files, got %v", cfg.Files)
+	}
+}
+
+func TestApply_FlagsOverrideConfig(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	template := fs.String("template", "default", "")
+	maxPages := fs.Int("max-pages", 10, "")
+	toc := fs.Bool("toc", false, "")
+	if err := fs.Parse([]string{"--template", "light"}); err != nil {
+		t.Fatal(err)
+	}
+
+	name, pages, enabled, depth := "paper", 3, true, 2
+	cfg := &Config{Template: &name, MaxPages: &pages, TOC: &enabled, TOCDepth: &depth}
+	if err := cfg.Apply(fs); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+
+	if *template != "light" {
+		t.Errorf("expected the command line template, got %q", *template)
+	}
+	if *maxPages != 3 || !*toc {
+		t.Errorf("expected config values for flags not on the command line, got %d, %v", *maxPages, *toc)
+	}
+}
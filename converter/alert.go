@@ -0,0 +1,142 @@
+package converter
+
+import (
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// alertType is one of the GitHub alert kinds, e.g. > [!NOTE]
+type alertType struct {
+	title string
+	icon  string // Octicon path, drawn in a 16x16 viewBox
+}
+
+// alertTypes maps the lower-cased alert marker to its title and icon
+var alertTypes = map[string]alertType{
+	"note":      {"Note", "M0 8a8 8 0 1 1 16 0A8 8 0 0 1 0 8Zm8-6.5a6.5 6.5 0 1 0 0 13 6.5 6.5 0 0 0 0-13ZM6.5 7.75A.75.75 0 0 1 7.25 7h1a.75.75 0 0 1 .75.75v2.75h.25a.75.75 0 0 1 0 1.5h-2a.75.75 0 0 1 0-1.5h.25v-2h-.25a.75.75 0 0 1-.75-.75ZM8 6a1 1 0 1 1 0-2 1 1 0 0 1 0 2Z"},
+	"tip":       {"Tip", "M8 1.5c-2.363 0-4 1.69-4 3.75 0 .984.424 1.625.984 2.304l.214.253c.223.264.47.556.673.848.284.411.537.896.621 1.49a.75.75 0 0 1-1.484.211c-.04-.282-.163-.547-.37-.847a8.456 8.456 0 0 0-.542-.68c-.084-.1-.173-.205-.268-.32C3.201 7.75 2.5 6.766 2.5 5.25 2.5 2.31 4.863 0 8 0s5.5 2.31 5.5 5.25c0 1.516-.701 2.5-1.328 3.259-.095.115-.184.22-.268.319-.207.245-.383.453-.541.681-.208.3-.33.565-.37.847a.751.751 0 0 1-1.485-.212c.084-.593.337-1.078.621-1.489.203-.292.45-.584.673-.848.075-.088.147-.173.213-.253.561-.679.985-1.32.985-2.304 0-2.06-1.637-3.75-4-3.75ZM5.75 12h4.5a.75.75 0 0 1 0 1.5h-4.5a.75.75 0 0 1 0-1.5ZM6 15.25a.75.75 0 0 1 .75-.75h2.5a.75.75 0 0 1 0 1.5h-2.5a.75.75 0 0 1-.75-.75Z"},
+	"important": {"Important", "M0 1.75C0 .784.784 0 1.75 0h12.5C15.216 0 16 .784 16 1.75v9.5A1.75 1.75 0 0 1 14.25 13H8.06l-2.573 2.573A1.458 1.458 0 0 1 3 14.543V13H1.75A1.75 1.75 0 0 1 0 11.25Zm1.75-.25a.25.25 0 0 0-.25.25v9.5c0 .138.112.25.25.25h2a.75.75 0 0 1 .75.75v2.19l2.72-2.72a.749.749 0 0 1 .53-.22h6.5a.25.25 0 0 0 .25-.25v-9.5a.25.25 0 0 0-.25-.25Zm7 2.25v2.5a.75.75 0 0 1-1.5 0v-2.5a.75.75 0 0 1 1.5 0ZM9 9a1 1 0 1 1-2 0 1 1 0 0 1 2 0Z"},
+	"warning":   {"Warning", "M6.457 1.047c.659-1.234 2.427-1.234 3.086 0l6.082 11.378A1.75 1.75 0 0 1 14.082 15H1.918a1.75 1.75 0 0 1-1.543-2.575Zm1.763.707a.25.25 0 0 0-.44 0L1.698 13.132a.25.25 0 0 0 .22.368h12.164a.25.25 0 0 0 .22-.368Zm.53 3.996v2.5a.75.75 0 0 1-1.5 0v-2.5a.75.75 0 0 1 1.5 0ZM9 11a1 1 0 1 1-2 0 1 1 0 0 1 2 0Z"},
+	"caution":   {"Caution", "M4.47.22A.749.749 0 0 1 5 0h6c.199 0 .389.079.53.22l4.25 4.25c.141.14.22.331.22.53v6a.749.749 0 0 1-.22.53l-4.25 4.25A.749.749 0 0 1 11 16H5a.749.749 0 0 1-.53-.22L.22 11.53A.749.749 0 0 1 0 11V5c0-.199.079-.389.22-.53Zm.84 1.28L1.5 5.31v5.38l3.81 3.81h5.38l3.81-3.81V5.31L10.69 1.5ZM8 4a.75.75 0 0 1 .75.75v3.5a.75.75 0 0 1-1.5 0v-3.5A.75.75 0 0 1 8 4Zm0 8a1 1 0 1 1 0-2 1 1 0 0 1 0 2Z"},
+}
+
+// kindAlert is the AST node kind for a GitHub alert
+var kindAlert = ast.NewNodeKind("Alert")
+
+// alertNode is a blockquote that started with an alert marker such as [!WARNING]
+type alertNode struct {
+	ast.BaseBlock
+	kind string // Key in alertTypes
+}
+
+// Kind implements ast.Node
+func (n *alertNode) Kind() ast.NodeKind {
+	return kindAlert
+}
+
+// Dump implements ast.Node
+func (n *alertNode) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Type": n.kind}, nil)
+}
+
+// alertTransformer turns blockquotes whose first line is [!NOTE], [!TIP], [!IMPORTANT],
+// [!WARNING] or [!CAUTION] into alert nodes, like GitHub does. The marker must be
+// alone on its line and followed by some content; otherwise the blockquote is left as is.
+type alertTransformer struct{}
+
+// Transform implements parser.ASTTransformer
+func (t *alertTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	source := reader.Source()
+
+	var quotes []*ast.Blockquote
+	_ = ast.Walk(doc, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if quote, ok := node.(*ast.Blockquote); ok && entering {
+			quotes = append(quotes, quote)
+		}
+		return ast.WalkContinue, nil
+	})
+
+	for _, quote := range quotes {
+		kind, ok := alertMarker(quote, source)
+		if !ok {
+			continue
+		}
+
+		// Drop the marker line, and the paragraph with it if that was all it held
+		para := quote.FirstChild().(*ast.Paragraph)
+		markerLine := para.Lines().At(0)
+		for child := para.FirstChild(); child != nil; {
+			next := child.NextSibling()
+			if textNode, ok := child.(*ast.Text); ok && textNode.Segment.Start < markerLine.Stop {
+				para.RemoveChild(para, child)
+			}
+			child = next
+		}
+		rest := text.NewSegments()
+		for i := 1; i < para.Lines().Len(); i++ {
+			rest.Append(para.Lines().At(i))
+		}
+		para.SetLines(rest)
+		if para.ChildCount() == 0 {
+			quote.RemoveChild(quote, para)
+		}
+
+		alert := &alertNode{kind: kind}
+		for child := quote.FirstChild(); child != nil; {
+			next := child.NextSibling()
+			alert.AppendChild(alert, child)
+			child = next
+		}
+		quote.Parent().ReplaceChild(quote.Parent(), quote, alert)
+	}
+}
+
+// alertMarker returns the alert type of a blockquote, if its first line is an alert marker
+func alertMarker(quote *ast.Blockquote, source []byte) (string, bool) {
+	para, ok := quote.FirstChild().(*ast.Paragraph)
+	if !ok || para.Lines().Len() == 0 {
+		return "", false
+	}
+
+	firstLine := para.Lines().At(0)
+	marker := strings.TrimSpace(string(firstLine.Value(source)))
+	if !strings.HasPrefix(marker, "[!") || !strings.HasSuffix(marker, "]") {
+		return "", false
+	}
+	kind := strings.ToLower(marker[len("[!") : len(marker)-len("]")])
+	if _, ok := alertTypes[kind]; !ok {
+		return "", false
+	}
+
+	// An alert needs content after the marker
+	if para.Lines().Len() == 1 && para.NextSibling() == nil {
+		return "", false
+	}
+	return kind, true
+}
+
+// alertRenderer renders alert nodes as callouts, using GitHub's markup
+type alertRenderer struct{}
+
+// RegisterFuncs implements renderer.NodeRenderer
+func (r *alertRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(kindAlert, r.renderAlert)
+}
+
+func (r *alertRenderer) renderAlert(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		_, _ = w.WriteString("</div>\n")
+		return ast.WalkContinue, nil
+	}
+
+	n := node.(*alertNode)
+	alert := alertTypes[n.kind]
+	_, _ = w.WriteString("<div class=\"markdown-alert markdown-alert-" + n.kind + "\">\n")
+	_, _ = w.WriteString("<p class=\"markdown-alert-title\"><svg class=\"octicon\" viewBox=\"0 0 16 16\" width=\"16\" height=\"16\" aria-hidden=\"true\"><path d=\"" + alert.icon + "\"></path></svg>" + alert.title + "</p>\n")
+	return ast.WalkContinue, nil
+}
@@ -0,0 +1,101 @@
+package converter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAlert_AllTypes(t *testing.T) {
+	tests := []struct {
+		marker string
+		class  string
+		title  string
+	}{
+		{"NOTE", "markdown-alert-note", "Note"},
+		{"TIP", "markdown-alert-tip", "Tip"},
+		{"IMPORTANT", "markdown-alert-important", "Important"},
+		{"WARNING", "markdown-alert-warning", "Warning"},
+		{"CAUTION", "markdown-alert-caution", "Caution"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.marker, func(t *testing.T) {
+			c := newWithExtensions(t, "alerts")
+			result := bodyOf(convert(t, c, "> [!"+tt.marker+"]\n> Body text\n"))
+
+			if !strings.Contains(result, `<div class="markdown-alert `+tt.class+`">`) {
+				t.Errorf("expected %s container, got:\n%s", tt.class, result)
+			}
+			if !strings.Contains(result, "</svg>"+tt.title+"</p>") {
+				t.Errorf("expected title %q with icon", tt.title)
+			}
+			if !strings.Contains(result, "<p>Body text</p>") {
+				t.Error("expected body to be rendered")
+			}
+			if strings.Contains(result, "[!"+tt.marker+"]") || strings.Contains(result, "<blockquote>") {
+				t.Error("expected marker and blockquote to be replaced")
+			}
+		})
+	}
+}
+
+func TestAlert_MarkerIsCaseInsensitive(t *testing.T) {
+	c := newWithExtensions(t, "alerts")
+	result := bodyOf(convert(t, c, "> [!warning]\n> Careful\n"))
+
+	if !strings.Contains(result, `markdown-alert-warning`) {
+		t.Error("expected lower-case marker to create an alert")
+	}
+}
+
+func TestAlert_KeepsBlockContent(t *testing.T) {
+	c := newWithExtensions(t, "alerts")
+	result := bodyOf(convert(t, c, "> [!TIP]\n> First *paragraph*\n>\n> - item one\n> - item two\n"))
+
+	wants := []string{
+		"<p>First <em>paragraph</em></p>",
+		"<li>item one</li>",
+		"<li>item two</li>",
+	}
+	for _, want := range wants {
+		if !strings.Contains(result, want) {
+			t.Errorf("expected %q in alert, got:\n%s", want, result)
+		}
+	}
+}
+
+func TestAlert_Nested(t *testing.T) {
+	c := newWithExtensions(t, "alerts")
+	result := bodyOf(convert(t, c, "- item\n\n  > [!NOTE]\n  > Nested in a list\n"))
+
+	if !strings.Contains(result, `<div class="markdown-alert markdown-alert-note">`) {
+		t.Errorf("expected alert inside list item, got:\n%s", result)
+	}
+}
+
+func TestAlert_NotAnAlert(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+	}{
+		{"plain quote", "> Just a quote\n"},
+		{"unknown type", "> [!DANGER]\n> Text\n"},
+		{"marker without content", "> [!NOTE]\n"},
+		{"text after marker", "> [!NOTE] inline text\n> more\n"},
+		{"marker not first", "> Text\n> [!NOTE]\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newWithExtensions(t, "alerts")
+			result := bodyOf(convert(t, c, tt.markdown))
+
+			if strings.Contains(result, "markdown-alert") {
+				t.Errorf("expected no alert, got:\n%s", result)
+			}
+			if !strings.Contains(result, "<blockquote>") {
+				t.Error("expected a regular blockquote")
+			}
+		})
+	}
+}
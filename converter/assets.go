@@ -0,0 +1,71 @@
+package converter
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// AssetURLPrefix starts the URL by which a document references an image in an AssetStore,
+// e.g. "mdview-asset:3f2a9c0d1e8b7a65". Whoever embeds the store resolves these URLs.
+const AssetURLPrefix = "mdview-asset:"
+
+// Asset is an image held by an AssetStore
+type Asset struct {
+	MimeType string
+	Data     []byte
+}
+
+// DataURI returns the asset as a base64 data URI
+func (a Asset) DataURI() string {
+	return fmt.Sprintf("data:%s;base64,%s", a.MimeType, base64.StdEncoding.EncodeToString(a.Data))
+}
+
+// AssetStore holds the images embedded by several conversions, keyed by a hash of their
+// content, so an image used by many documents (e.g. the pages of an archive) is stored once.
+// It is safe for concurrent use.
+type AssetStore struct {
+	mu     sync.Mutex
+	assets map[string]Asset
+}
+
+// NewAssetStore creates an empty asset store
+func NewAssetStore() *AssetStore {
+	return &AssetStore{assets: make(map[string]Asset)}
+}
+
+// Add stores an image, unless the same content is already stored, and returns its ID
+func (s *AssetStore) Add(mimeType string, data []byte) string {
+	sum := sha256.Sum256(data)
+	id := hex.EncodeToString(sum[:8])
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.assets[id]; !exists {
+		s.assets[id] = Asset{MimeType: mimeType, Data: data}
+	}
+	return id
+}
+
+// Get returns the asset with the given ID
+func (s *AssetStore) Get(id string) (Asset, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	asset, ok := s.assets[id]
+	return asset, ok
+}
+
+// IDs returns the IDs of all stored assets, sorted
+func (s *AssetStore) IDs() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids := make([]string, 0, len(s.assets))
+	for id := range s.assets {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
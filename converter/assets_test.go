@@ -0,0 +1,77 @@
+package converter
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAssetStore_DeduplicatesByContent(t *testing.T) {
+	store := NewAssetStore()
+	a := store.Add("image/png", []byte("logo"))
+	b := store.Add("image/png", []byte("logo"))
+	c := store.Add("image/png", []byte("diagram"))
+
+	if a != b {
+		t.Errorf("expected the same ID for the same content, got %q and %q", a, b)
+	}
+	if a == c {
+		t.Error("expected different IDs for different content")
+	}
+	if ids := store.IDs(); len(ids) != 2 {
+		t.Errorf("expected 2 stored assets, got %v", ids)
+	}
+
+	asset, ok := store.Get(a)
+	if !ok || asset.MimeType != "image/png" || string(asset.Data) != "logo" {
+		t.Errorf("unexpected asset %+v (found: %t)", asset, ok)
+	}
+	if uri := asset.DataURI(); uri != "data:image/png;base64,bG9nbw==" {
+		t.Errorf("unexpected data URI %q", uri)
+	}
+}
+
+func TestConverter_AssetStore(t *testing.T) {
+	dir, cleanup := setupTestDir(t)
+	defer cleanup()
+
+	// The same image under another name is the same asset
+	png, err := os.ReadFile(filepath.Join(dir, "test.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "copy.png"), png, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewAssetStore()
+	markdown := "![one](test.png)\n\n<img src=\"copy.png\">\n\n![two](test.jpg)\n"
+	var results []string
+	for range 2 {
+		c := New()
+		c.SetBaseDir(dir)
+		c.SetSelfContained(true)
+		c.SetAssetStore(store)
+		results = append(results, convert(t, c, markdown))
+	}
+
+	ids := store.IDs()
+	if len(ids) != 2 {
+		t.Fatalf("expected 2 stored assets, got %v", ids)
+	}
+	for _, result := range results {
+		if strings.Contains(result, "data:image/") {
+			t.Errorf("expected no inline data URIs with an asset store, got: %s", result)
+		}
+		for _, id := range ids {
+			if !strings.Contains(result, `src="`+AssetURLPrefix+id+`"`) {
+				t.Errorf("expected a reference to asset %s, got: %s", id, result)
+			}
+		}
+	}
+	if !reflect.DeepEqual(results[0], results[1]) {
+		t.Error("expected conversions sharing a store to reference the same assets")
+	}
+}
@@ -3,10 +3,12 @@ package converter
 import (
 	"bufio"
 	"bytes"
+	_ "embed"
 	"encoding/base64"
 	"fmt"
 	htmlpkg "html"
 	"io"
+	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -19,11 +21,15 @@ import (
 	"github.com/yuin/goldmark/parser"
 	"github.com/yuin/goldmark/renderer"
 	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
 	"github.com/yuin/goldmark/util"
 
 	"mdview/templates"
 )
 
+//go:embed livereload.js
+var liveReloadJS string
+
 // ImageCache holds preloaded image data for faster embedding
 type ImageCache struct {
 	data            sync.Map // map[string][]byte - path -> file contents
@@ -135,7 +141,19 @@ type Converter struct {
 	archiveMode       bool        // Keep .md links as relative paths for archive navigation
 	archiveRootDir    string      // Root directory of the archive (for computing relative paths)
 	imageCache        *ImageCache // Cache for preloaded images (only used when preload is enabled)
+	assets            *AssetStore // Store for embedded images shared with other conversions (nil = inline data URIs)
 	title             string      // Custom page title (replaces template default)
+	serveRoot         string      // Root directory of the preview server (local paths become server URLs)
+	siteRoot          string      // Root directory of a batch conversion (local links stay relative, .md becomes .html)
+	liveReloadSrc     string      // URL of the live reload sidecar script (empty = disabled)
+	liveReloadToken   string      // Token identifying this build for live reload
+	metadata          *Metadata   // Front matter of the last converted document (nil if none)
+	tocSidebar        bool        // Render a table of contents sidebar next to the article
+	tocDepth          int         // Deepest heading level included in tables of contents (0 = DefaultTOCDepth)
+	headings          []Heading   // Headings of the last converted document
+	highlight         bool        // Highlight code blocks at conversion time instead of with highlight.js
+	extensions        Extensions  // Markdown extensions to enable
+	sourcePath        string      // Path of the markdown file, for template layouts
 }
 
 // Regex patterns for finding src and href attributes in raw HTML
@@ -152,7 +170,9 @@ var (
 
 // New creates a new Converter instance
 func New() *Converter {
-	return &Converter{}
+	return &Converter{
+		extensions: DefaultExtensions(),
+	}
 }
 
 // SetBaseDir sets the base directory for resolving relative paths in the output.
@@ -178,6 +198,14 @@ func (c *Converter) SetPreload(enabled bool) {
 	}
 }
 
+// SetAssetStore makes embedded images go into store, referenced from the output by
+// AssetURLPrefix + ID instead of as inline data URIs, so conversions sharing the store
+// embed a shared image only once. The caller must embed the store in the final output and
+// resolve the references. Only takes effect with SetSelfContained.
+func (c *Converter) SetAssetStore(store *AssetStore) {
+	c.assets = store
+}
+
 // SetArchiveMode enables archive mode where .md links are converted to
 // javascript:mdviewLoadPage('...') calls with archive-relative paths.
 func (c *Converter) SetArchiveMode(enabled bool) {
@@ -196,40 +224,150 @@ func (c *Converter) SetTitle(title string) {
 	c.title = title
 }
 
+// SetServeRoot enables server mode, used by `mdview serve`. Local links and images that
+// resolve inside dir are rewritten to root-relative server URLs (e.g. /docs/guide.md)
+// instead of file:// URLs, and such links open in the same tab.
+func (c *Converter) SetServeRoot(dir string) {
+	c.serveRoot = dir
+}
+
+// SetSiteRoot enables site mode, used for batch conversion of a directory into a mirrored
+// HTML tree. Relative links and images that resolve inside dir are kept relative, and links
+// to .md files point to the corresponding .html page instead, keeping any #fragment or ?query.
+func (c *Converter) SetSiteRoot(dir string) {
+	c.siteRoot = dir
+}
+
+// SetLiveReload injects a script into the footer that polls the sidecar script at src
+// and reloads the page when the sidecar reports a token different from token.
+// Pass an empty src to disable live reload.
+func (c *Converter) SetLiveReload(src, token string) {
+	c.liveReloadSrc = src
+	c.liveReloadToken = token
+}
+
+// SetTOCSidebar enables a table of contents sidebar built from the document's headings.
+// Independently of this setting, a [TOC] or <!-- toc --> marker on its own line is
+// replaced with a table of contents in place.
+func (c *Converter) SetTOCSidebar(enabled bool) {
+	c.tocSidebar = enabled
+}
+
+// SetTOCDepth sets the deepest heading level (1-6) included in tables of contents.
+// If depth is 0, DefaultTOCDepth is used.
+func (c *Converter) SetTOCDepth(depth int) {
+	c.tocDepth = depth
+}
+
+// SetHighlight enables syntax highlighting of fenced code blocks at conversion time.
+// The output uses the same hljs-* classes as highlight.js, so the template theme still
+// applies, and the template's highlight.js is left out of the page.
+func (c *Converter) SetHighlight(enabled bool) {
+	c.highlight = enabled
+}
+
+// SetExtensions selects the markdown extensions used for conversion.
+// New converters use DefaultExtensions.
+func (c *Converter) SetExtensions(ext Extensions) {
+	c.extensions = ext
+}
+
+// SetSourcePath sets the path of the markdown file being converted, which template
+// layouts can show as {{.Source}}. It doesn't affect how paths are resolved; see SetBaseDir.
+func (c *Converter) SetSourcePath(path string) {
+	c.sourcePath = path
+}
+
+// Headings returns the headings of the most recently converted document
+func (c *Converter) Headings() []Heading {
+	return c.headings
+}
+
+// Metadata returns the front matter of the most recently converted document,
+// or nil if it had none.
+func (c *Converter) Metadata() *Metadata {
+	return c.metadata
+}
+
 // createMarkdown builds a goldmark instance with appropriate settings
 func (c *Converter) createMarkdown() goldmark.Markdown {
+	ext := c.extensions
+
+	htmlOptions := []renderer.Option{
+		html.WithXHTML(),
+		html.WithUnsafe(), // Allow raw HTML in markdown
+	}
+	if ext.HardWraps {
+		htmlOptions = append(htmlOptions, html.WithHardWraps())
+	}
+
+	extensions := []goldmark.Extender{
+		extension.GFM, // GitHub Flavored Markdown
+	}
+	if ext.Typographer {
+		extensions = append(extensions, extension.Typographer)
+	}
+	if ext.Footnotes {
+		extensions = append(extensions, extension.Footnote)
+	}
+	if ext.DefinitionLists {
+		extensions = append(extensions, extension.DefinitionList)
+	}
+	if ext.CJK {
+		extensions = append(extensions, extension.CJK)
+	}
+
+	parserOptions := []parser.Option{
+		parser.WithAutoHeadingID(),
+	}
+	if ext.Math {
+		parserOptions = append(parserOptions,
+			parser.WithBlockParsers(util.Prioritized(&mathBlockParser{}, 700)),
+			parser.WithInlineParsers(util.Prioritized(&mathInlineParser{}, 500)),
+		)
+	}
+	if ext.Alerts {
+		parserOptions = append(parserOptions, parser.WithASTTransformers(util.Prioritized(&alertTransformer{}, 100)))
+	}
+
+	nodeRenderers := []util.PrioritizedValue{
+		util.Prioritized(html.NewRenderer(), 1000),
+		util.Prioritized(&pathRenderer{
+			baseDir:        c.baseDir,
+			selfContained:  c.selfContained,
+			preload:        c.preload,
+			archiveMode:    c.archiveMode,
+			archiveRootDir: c.archiveRootDir,
+			serveRoot:      c.serveRoot,
+			siteRoot:       c.siteRoot,
+			imageCache:     c.imageCache,
+			assets:         c.assets,
+		}, 100), // Higher priority (lower number) for our custom renderer
+		util.Prioritized(&tocRenderer{}, 100),
+		util.Prioritized(newMathRenderer(), 100),
+		util.Prioritized(&alertRenderer{}, 100),
+	}
+
+	// Fenced code is rendered as a diagram, highlighted code or plain code, in that order
+	codeBlock := renderPlainFencedCodeBlock
+	if c.highlight {
+		codeBlock = (&highlightRenderer{}).renderFencedCodeBlock
+	}
+	if ext.Diagrams {
+		codeBlock = (&diagramRenderer{fallback: codeBlock}).renderFencedCodeBlock
+	}
+	nodeRenderers = append(nodeRenderers, util.Prioritized(codeBlockRenderer(codeBlock), 100))
+
 	return goldmark.New(
-		goldmark.WithExtensions(
-			extension.GFM, // GitHub Flavored Markdown
-			extension.Typographer,
-		),
-		goldmark.WithParserOptions(
-			parser.WithAutoHeadingID(),
-		),
-		goldmark.WithRendererOptions(
-			html.WithHardWraps(),
-			html.WithXHTML(),
-			html.WithUnsafe(), // Allow raw HTML in markdown
-		),
+		goldmark.WithExtensions(extensions...),
+		goldmark.WithParserOptions(parserOptions...),
 		goldmark.WithRenderer(
 			renderer.NewRenderer(
-				renderer.WithNodeRenderers(
-					util.Prioritized(html.NewRenderer(
-						html.WithHardWraps(),
-						html.WithXHTML(),
-						html.WithUnsafe(),
-					), 1000),
-					util.Prioritized(&pathRenderer{
-						baseDir:        c.baseDir,
-						selfContained:  c.selfContained,
-						preload:        c.preload,
-						archiveMode:    c.archiveMode,
-						archiveRootDir: c.archiveRootDir,
-						imageCache:     c.imageCache,
-					}, 100), // Higher priority (lower number) for our custom renderer
-				),
+				renderer.WithNodeRenderers(nodeRenderers...),
 			),
 		),
+		// Must come after WithRenderer: options apply to the renderer set at the time
+		goldmark.WithRendererOptions(htmlOptions...),
 	)
 }
 
@@ -253,23 +391,27 @@ func (c *Converter) ConvertWithSize(reader io.Reader, writer io.Writer, template
 	// Use buffered writer for efficient streaming output
 	bufWriter := bufio.NewWriter(writer)
 
-	// Write HTML header
-	if err := c.writeHeader(bufWriter, tmpl); err != nil {
-		return err
-	}
-
 	// Read markdown content using pooled buffer
 	source, err := c.readSource(reader, sizeHint)
 	if err != nil {
 		return fmt.Errorf("failed to read markdown: %w", err)
 	}
 
+	// Front matter feeds the header, so it must be parsed before the header is written
+	var body []byte
+	c.metadata, body = parseFrontMatter(source)
+
 	// Create markdown converter with current settings (handles images during rendering)
 	md := c.createMarkdown()
 
+	// Parse and render separately so headings can be collected for tables of contents
+	doc := md.Parser().Parse(text.NewReader(body))
+	c.headings = collectHeadings(doc, body)
+	replaceTOCMarkers(doc, body, filterHeadings(c.headings, c.tocDepth))
+
 	// Convert markdown to HTML - buffer for href rewriting (images handled inline)
 	var htmlBuf bytes.Buffer
-	convertErr := md.Convert(source, &htmlBuf)
+	convertErr := md.Renderer().Render(&htmlBuf, body, doc)
 
 	// Release source buffer back to pool immediately after conversion
 	c.releaseBuffer(source)
@@ -278,13 +420,8 @@ func (c *Converter) ConvertWithSize(reader io.Reader, writer io.Writer, template
 		return fmt.Errorf("failed to convert markdown: %w", convertErr)
 	}
 
-	// Write the HTML content (all paths handled during rendering)
-	if _, err := io.WriteString(bufWriter, htmlBuf.String()); err != nil {
-		return err
-	}
-
-	// Write HTML footer
-	if err := c.writeFooter(bufWriter, tmpl); err != nil {
+	// Write the page layout around the HTML content (all paths handled during rendering)
+	if err := c.writePage(bufWriter, templateName, tmpl, htmlBuf.String()); err != nil {
 		return err
 	}
 
@@ -299,7 +436,10 @@ type pathRenderer struct {
 	preload        bool
 	archiveMode    bool
 	archiveRootDir string
+	serveRoot      string
+	siteRoot       string
 	imageCache     *ImageCache
+	assets         *AssetStore
 }
 
 // RegisterFuncs implements renderer.NodeRenderer
@@ -360,8 +500,8 @@ func (r *pathRenderer) renderLink(w util.BufWriter, source []byte, node ast.Node
 		_, _ = w.WriteString(htmlpkg.EscapeString(finalDest))
 		_, _ = w.WriteString("\"")
 
-		// Open external links in new tab (not javascript: or anchors)
-		if !strings.HasPrefix(finalDest, "javascript:") && !strings.HasPrefix(finalDest, "#") {
+		// Open external links in new tab (not javascript:, anchors or server pages)
+		if r.opensInNewTab(finalDest) {
 			_, _ = w.WriteString(" target=\"_blank\"")
 		}
 
@@ -433,9 +573,7 @@ func (r *pathRenderer) processRawHTMLContent(content string) string {
 		processedPath := r.processLinkPath(path)
 
 		// Add target="_blank" for non-javascript, non-anchor links (if not already has target)
-		if !strings.HasPrefix(processedPath, "javascript:") &&
-			!strings.HasPrefix(processedPath, "#") &&
-			!strings.Contains(middle, "target=") {
+		if r.opensInNewTab(processedPath) && !strings.Contains(middle, "target=") {
 			return prefix + processedPath + middle + " target=\"_blank\"" + suffix
 		}
 		return prefix + processedPath + middle + suffix
@@ -457,6 +595,99 @@ func (r *pathRenderer) processRawHTMLContent(content string) string {
 	return content
 }
 
+// opensInNewTab reports whether a rewritten link should get target="_blank".
+// In-page anchors, archive navigation, pages on the preview server and relative links
+// between the pages of a batch conversion stay in the same tab.
+func (r *pathRenderer) opensInNewTab(dest string) bool {
+	if strings.HasPrefix(dest, "javascript:") || strings.HasPrefix(dest, "#") {
+		return false
+	}
+	if r.serveRoot != "" && strings.HasPrefix(dest, "/") {
+		return false
+	}
+	if r.siteRoot != "" && !strings.Contains(dest, ":") {
+		return false
+	}
+	return true
+}
+
+// serverURL maps an absolute local path to a root-relative URL on the preview server.
+// suffix (a #fragment or ?query) is appended unchanged. Returns false if server mode
+// is off or the path lies outside the served directory.
+func (r *pathRenderer) serverURL(absPath, suffix string) (string, bool) {
+	if r.serveRoot == "" {
+		return "", false
+	}
+
+	relPath, err := filepath.Rel(r.serveRoot, absPath)
+	if err != nil || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+
+	u := url.URL{Path: "/" + filepath.ToSlash(relPath)}
+	if relPath == "." {
+		u.Path = "/"
+	}
+	return u.EscapedPath() + suffix, true
+}
+
+// siteLink maps a relative link to its form in the output tree of a batch conversion:
+// links to .md files point to the converted .html page and other files are linked as is.
+// Returns false if site mode is off, or the link is not relative or leads outside the site root.
+func (r *pathRenderer) siteLink(path string) (string, bool) {
+	if r.siteRoot == "" || r.baseDir == "" || path == "" ||
+		strings.Contains(path, ":") || strings.HasPrefix(path, "/") || strings.HasPrefix(path, "#") {
+		return "", false
+	}
+
+	target, suffix := splitPathSuffix(path)
+	unescaped := target
+	if u, err := url.PathUnescape(target); err == nil {
+		unescaped = u
+	}
+	absPath := filepath.Clean(filepath.Join(r.baseDir, unescaped))
+
+	relPath, err := filepath.Rel(r.siteRoot, absPath)
+	if err != nil || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+
+	if ext := filepath.Ext(target); strings.EqualFold(ext, ".md") {
+		target = strings.TrimSuffix(target, ext) + ".html"
+	}
+	return target + suffix, true
+}
+
+// splitPathSuffix splits a link into its path and any trailing ?query or #fragment
+func splitPathSuffix(path string) (string, string) {
+	if i := strings.IndexAny(path, "?#"); i != -1 {
+		return path[:i], path[i:]
+	}
+	return path, ""
+}
+
+// linkFragment returns the unescaped #fragment of a link suffix from splitPathSuffix ("" if none)
+func linkFragment(suffix string) string {
+	i := strings.Index(suffix, "#")
+	if i == -1 {
+		return ""
+	}
+	fragment := suffix[i+1:]
+	if unescaped, err := url.PathUnescape(fragment); err == nil {
+		fragment = unescaped
+	}
+	return fragment
+}
+
+// jsStringEscaper escapes text for a single-quoted JavaScript string
+var jsStringEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+// EscapeJSString escapes s for use in a single-quoted JavaScript string, such as the
+// archive key in a javascript:mdviewLoadPage('...') link
+func EscapeJSString(s string) string {
+	return jsStringEscaper.Replace(s)
+}
+
 // processCSSAssetPath handles path resolution or base64 embedding for CSS assets
 func (r *pathRenderer) processCSSAssetPath(path string) string {
 	if strings.HasPrefix(path, "data:") ||
@@ -490,27 +721,39 @@ func (r *pathRenderer) processCSSAssetPath(path string) string {
 		return path
 	}
 
-	encoded := base64.StdEncoding.EncodeToString(assetData)
-	return fmt.Sprintf("data:%s;base64,%s", mimeType, encoded)
+	return r.embedURL(mimeType, assetData)
+}
+
+// embedURL returns the URL that embeds data: a reference into the asset store if there
+// is one, otherwise a base64 data URI
+func (r *pathRenderer) embedURL(mimeType string, data []byte) string {
+	if r.assets != nil {
+		return AssetURLPrefix + r.assets.Add(mimeType, data)
+	}
+	return Asset{MimeType: mimeType, Data: data}.DataURI()
 }
 
 // processLinkPath handles path resolution for links (no embedding, just file:// conversion)
 func (r *pathRenderer) processLinkPath(path string) string {
 	// In archive mode, convert ALL .md links to javascript:mdviewLoadPage() calls
 	// This must happen FIRST, before any other checks, to catch file:// URLs too
-	if r.archiveMode && r.archiveRootDir != "" && strings.HasSuffix(strings.ToLower(path), ".md") {
+	target, suffix := splitPathSuffix(path)
+	if r.archiveMode && r.archiveRootDir != "" && strings.HasSuffix(strings.ToLower(target), ".md") {
 		var absPath string
+		if unescaped, err := url.PathUnescape(target); err == nil {
+			target = unescaped
+		}
 
 		// Handle file:// URLs
-		if strings.HasPrefix(path, "file:///") {
-			absPath = strings.TrimPrefix(path, "file:///")
+		if strings.HasPrefix(target, "file:///") {
+			absPath = strings.TrimPrefix(target, "file:///")
 			absPath = filepath.FromSlash(absPath)
-		} else if strings.Contains(path, "://") {
+		} else if strings.Contains(target, "://") {
 			// Other protocols with .md - skip (e.g., https://example.com/doc.md)
 			return path
 		} else if r.baseDir != "" {
 			// Relative path - resolve against base directory
-			absPath = filepath.Join(r.baseDir, path)
+			absPath = filepath.Join(r.baseDir, target)
 		} else {
 			// No base dir, can't resolve
 			return path
@@ -528,8 +771,12 @@ func (r *pathRenderer) processLinkPath(path string) string {
 		// Normalize to forward slashes for consistency
 		relPath = strings.ReplaceAll(relPath, "\\", "/")
 
-		// Return javascript: href with the archive key
-		return "javascript:mdviewLoadPage('" + relPath + "')"
+		// Return javascript: href with the archive key, and the heading to scroll to if the
+		// link has a #fragment (a ?query means nothing to an embedded page and is dropped)
+		if anchor := linkFragment(suffix); anchor != "" {
+			return "javascript:mdviewLoadPage('" + EscapeJSString(relPath) + "', '" + EscapeJSString(anchor) + "')"
+		}
+		return "javascript:mdviewLoadPage('" + EscapeJSString(relPath) + "')"
 	}
 
 	// Skip if already absolute or special protocol
@@ -546,10 +793,26 @@ func (r *pathRenderer) processLinkPath(path string) string {
 		return path
 	}
 
-	// Resolve relative path and convert to file:// URL
-	absPath := filepath.Join(r.baseDir, path)
+	// In site mode, link to the page or file in the output tree
+	if link, ok := r.siteLink(path); ok {
+		return link
+	}
+
+	// In server mode, link to the page on the preview server
+	if r.serveRoot != "" {
+		target, suffix := splitPathSuffix(path)
+		if unescaped, err := url.PathUnescape(target); err == nil {
+			target = unescaped
+		}
+		if serverURL, ok := r.serverURL(filepath.Clean(filepath.Join(r.baseDir, target)), suffix); ok {
+			return serverURL
+		}
+	}
+
+	// Resolve relative path and convert to file:// URL, keeping any ?query or #fragment
+	absPath := filepath.Join(r.baseDir, target)
 	absPath = filepath.Clean(absPath)
-	return "file:///" + strings.ReplaceAll(absPath, "\\", "/")
+	return "file:///" + strings.ReplaceAll(absPath, "\\", "/") + suffix
 }
 
 // processImagePath handles path resolution or base64 embedding for an image
@@ -614,13 +877,22 @@ func (r *pathRenderer) processImagePath(path string) string {
 				}
 			}
 
-			encoded := base64.StdEncoding.EncodeToString(imageData)
-			return fmt.Sprintf("data:%s;base64,%s", mimeType, encoded)
+			return r.embedURL(mimeType, imageData)
 		}
 		// Fall through to file:// URL if no mime type
 	}
 
 fileURL:
+	// In site mode, the image is copied into the output tree next to the page
+	if link, ok := r.siteLink(path); ok {
+		return link
+	}
+
+	// In server mode, load the image from the preview server
+	if serverURL, ok := r.serverURL(absPath, ""); ok {
+		return serverURL
+	}
+
 	// Convert to file:// URL
 	fileURL := "file:///" + strings.ReplaceAll(absPath, "\\", "/")
 	return fileURL
@@ -772,82 +1044,3 @@ func getCSSAssetMimeType(path string) string {
 		return ""
 	}
 }
-
-// writeHeader writes the HTML document header with embedded template content
-func (c *Converter) writeHeader(w io.Writer, tmpl *templates.Template) error {
-	if _, err := io.WriteString(w, `<!DOCTYPE html>
-<html lang="en">
-<head>
-<meta charset="UTF-8">
-<meta name="viewport" content="width=device-width, initial-scale=1.0">
-`); err != nil {
-		return err
-	}
-
-	if tmpl.HTML != "" {
-		templateHTML := tmpl.HTML
-		// Replace title if custom title is set
-		if c.title != "" {
-			newTitle := "<title>" + htmlpkg.EscapeString(c.title) + "</title>"
-			templateHTML = titlePattern.ReplaceAllString(templateHTML, newTitle)
-		}
-		if _, err := io.WriteString(w, templateHTML); err != nil {
-			return err
-		}
-		if _, err := io.WriteString(w, "\n"); err != nil {
-			return err
-		}
-	}
-
-	if tmpl.CSS != "" {
-		if _, err := io.WriteString(w, "<style>\n"); err != nil {
-			return err
-		}
-		css := tmpl.CSS
-		if c.selfContained && c.baseDir != "" {
-			css = c.embedCSSAssets(css)
-		}
-		if _, err := io.WriteString(w, css); err != nil {
-			return err
-		}
-		if _, err := io.WriteString(w, "\n</style>\n"); err != nil {
-			return err
-		}
-	}
-
-	if _, err := io.WriteString(w, `</head>
-<body>
-<article class="markdown-body">
-`); err != nil {
-		return err
-	}
-
-	return nil
-}
-
-// writeFooter writes the HTML document footer with embedded template JS
-func (c *Converter) writeFooter(w io.Writer, tmpl *templates.Template) error {
-	if _, err := io.WriteString(w, "\n</article>\n"); err != nil {
-		return err
-	}
-
-	if tmpl.JS != "" {
-		if _, err := io.WriteString(w, "<script>\n"); err != nil {
-			return err
-		}
-		if _, err := io.WriteString(w, tmpl.JS); err != nil {
-			return err
-		}
-		if _, err := io.WriteString(w, "\n</script>\n"); err != nil {
-			return err
-		}
-	}
-
-	if _, err := io.WriteString(w, `</body>
-</html>
-`); err != nil {
-		return err
-	}
-
-	return nil
-}
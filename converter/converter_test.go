@@ -79,6 +79,19 @@ func setupTestDir(t *testing.T) (string, func()) {
 	return dir, func() { os.RemoveAll(dir) }
 }
 
+// newWithExtensions creates a converter with the extensions in spec (ParseExtensions syntax)
+// enabled on top of the defaults
+func newWithExtensions(t *testing.T, spec string) *Converter {
+	t.Helper()
+	ext, err := ParseExtensions(spec, DefaultExtensions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := New()
+	c.SetExtensions(ext)
+	return c
+}
+
 // convert is a helper that runs conversion and returns the body content
 func convert(t *testing.T, c *Converter, markdown string) string {
 	t.Helper()
@@ -1735,6 +1748,37 @@ func TestArchiveMode_KeepsMarkdownLinksRelative(t *testing.T) {
 	}
 }
 
+func TestArchiveMode_KeepsFragments(t *testing.T) {
+	dir, cleanup := setupTestDir(t)
+	defer cleanup()
+
+	markdown := `[Install](guide/setup.md#install)
+[Query](guide/setup.md?plain=1#first-steps)
+[Escaped](My%20Notes.md#caf%C3%A9)
+<a href="guide/setup.md#raw-html">Raw</a>
+[PDF](manual.pdf#page=2)
+`
+
+	c := New()
+	c.SetBaseDir(dir)
+	c.SetArchiveMode(true)
+	c.SetArchiveRootDir(dir)
+
+	result := convert(t, c, markdown)
+
+	for _, want := range []string{
+		`href="javascript:mdviewLoadPage(&#39;guide/setup.md&#39;, &#39;install&#39;)"`,
+		`href="javascript:mdviewLoadPage(&#39;guide/setup.md&#39;, &#39;first-steps&#39;)"`,
+		`href="javascript:mdviewLoadPage(&#39;My Notes.md&#39;, &#39;café&#39;)"`,
+		`href="javascript:mdviewLoadPage('guide/setup.md', 'raw-html')"`,
+		`manual.pdf#page=2"`,
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("expected %s in archive mode, got: %s", want, result)
+		}
+	}
+}
+
 func TestArchiveMode_Disabled(t *testing.T) {
 	dir, cleanup := setupTestDir(t)
 	defer cleanup()
@@ -1912,3 +1956,149 @@ func TestSetTitle_DoesNotAffectNonSelfContained(t *testing.T) {
 		t.Errorf("expected custom title in non-self-contained output, got:\n%s", result)
 	}
 }
+
+// =============================================================================
+// Live Reload Tests
+// =============================================================================
+
+func TestSetLiveReload_InjectsScript(t *testing.T) {
+	c := New()
+	c.SetLiveReload("out.html.reload.js", "abc123")
+
+	result := convert(t, c, "# Hello")
+
+	if !strings.Contains(result, `window.mdviewLiveReload = {src: "out.html.reload.js", token: "abc123"}`) {
+		t.Errorf("expected live reload config in output, got:\n%s", result)
+	}
+	if !strings.Contains(result, "mdviewLiveReloadCheck") {
+		t.Error("expected live reload script in output")
+	}
+
+	// Script must be in the footer, after the article
+	if strings.Index(result, "mdviewLiveReload") < strings.Index(result, "</article>") {
+		t.Error("expected live reload script after </article>")
+	}
+}
+
+func TestSetLiveReload_DisabledByDefault(t *testing.T) {
+	c := New()
+
+	result := convert(t, c, "# Hello")
+
+	if strings.Contains(result, "mdviewLiveReload") {
+		t.Error("expected no live reload script when not enabled")
+	}
+}
+
+// =============================================================================
+// Serve Mode Tests
+// =============================================================================
+
+func TestServeRoot_RewritesLocalPathsToServerURLs(t *testing.T) {
+	dir, cleanup := setupTestDir(t)
+	defer cleanup()
+
+	c := New()
+	c.SetBaseDir(filepath.Join(dir, "docs"))
+	c.SetServeRoot(dir)
+
+	result := convert(t, c, `[guide](guide.md#setup) [up](../other.md) [space](my%20file.md) [ext](https://example.com)
+
+![img](../test.png)
+
+<a href="guide.md">raw</a>`)
+
+	wants := []string{
+		`href="/docs/guide.md#setup"`,
+		`href="/other.md"`,
+		`href="/docs/my%20file.md"`,
+		`href="https://example.com" target="_blank"`,
+		`src="/test.png"`,
+		`<a href="/docs/guide.md">raw</a>`,
+	}
+	for _, want := range wants {
+		if !strings.Contains(result, want) {
+			t.Errorf("expected %q in output", want)
+		}
+	}
+
+	// Server links stay in the same tab
+	if strings.Contains(result, `href="/docs/guide.md#setup" target="_blank"`) {
+		t.Error("expected server links to open in the same tab")
+	}
+}
+
+func TestServeRoot_PathsOutsideRootUseFileURLs(t *testing.T) {
+	dir, cleanup := setupTestDir(t)
+	defer cleanup()
+
+	c := New()
+	c.SetBaseDir(dir)
+	c.SetServeRoot(filepath.Join(dir, "docs"))
+
+	result := convert(t, c, "[outside](other.md)")
+
+	if !strings.Contains(result, `href="file:///`) {
+		t.Error("expected links outside the served directory to fall back to file:// URLs")
+	}
+}
+
+// =============================================================================
+// Site Mode Tests
+// =============================================================================
+
+func TestSiteRoot_RewritesMarkdownLinksToHTML(t *testing.T) {
+	dir, cleanup := setupTestDir(t)
+	defer cleanup()
+
+	c := New()
+	c.SetBaseDir(filepath.Join(dir, "docs"))
+	c.SetSiteRoot(dir)
+
+	result := convert(t, c, `[guide](guide.md#setup) [up](../other.MD?v=1) [space](my%20file.md) [pdf](spec.pdf) [ext](https://example.com/a.md)
+
+![img](../test.png)
+
+<a href="guide.md">raw</a>`)
+
+	wants := []string{
+		`href="guide.html#setup"`,
+		`href="../other.html?v=1"`,
+		`href="my%20file.html"`,
+		`href="spec.pdf"`,
+		`href="https://example.com/a.md" target="_blank"`,
+		`src="../test.png"`,
+		`<a href="guide.html">raw</a>`,
+	}
+	for _, want := range wants {
+		if !strings.Contains(result, want) {
+			t.Errorf("expected %q in output", want)
+		}
+	}
+
+	// Links between the pages stay in the same tab
+	if strings.Contains(result, `href="guide.html#setup" target="_blank"`) {
+		t.Error("expected site links to open in the same tab")
+	}
+}
+
+func TestSiteRoot_PathsOutsideRootUseFileURLs(t *testing.T) {
+	dir, cleanup := setupTestDir(t)
+	defer cleanup()
+
+	c := New()
+	c.SetBaseDir(filepath.Join(dir, "docs"))
+	c.SetSiteRoot(filepath.Join(dir, "docs"))
+
+	result := convert(t, c, "[outside](../other.md)\n\n![img](../test.png)")
+
+	if !strings.Contains(result, `href="file:///`) || !strings.Contains(result, `src="file:///`) {
+		t.Errorf("expected paths outside the site root to fall back to file:// URLs, got:\n%s", result)
+	}
+}
+
+func TestEscapeJSString(t *testing.T) {
+	if got := EscapeJSString(`docs\it's.md`); got != `docs\\it\'s.md` {
+		t.Errorf("EscapeJSString() = %s", got)
+	}
+}
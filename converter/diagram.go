@@ -0,0 +1,169 @@
+package converter
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	htmlpkg "html"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/util"
+)
+
+// diagramEngine is a command line tool that reads diagram source on stdin and writes SVG to stdout
+type diagramEngine struct {
+	command string
+	args    []string
+	idFlag  string // Flag that sets the SVG element id, so several diagrams' styles don't clash on one page
+	class   string // Class of the <pre> holding the source when the tool is missing, for a client-side renderer ("" = none)
+}
+
+// diagramEngines maps fenced code block languages to the tool that renders them
+var diagramEngines = map[string]diagramEngine{
+	"mermaid":  {"mmdc", []string{"--input", "-", "--output", "-", "--outputFormat", "svg", "--quiet"}, "--svgId", "mermaid"},
+	"dot":      {"dot", []string{"-Tsvg"}, "", "graphviz"},
+	"graphviz": {"dot", []string{"-Tsvg"}, "", "graphviz"},
+	"plantuml": {"plantuml", []string{"-tsvg", "-pipe"}, "", ""},
+	"puml":     {"plantuml", []string{"-tsvg", "-pipe"}, "", ""},
+}
+
+// diagramTimeout bounds a single diagram render (mmdc starts a headless browser, so it's slow)
+const diagramTimeout = 30 * time.Second
+
+// diagramCache holds rendered SVG by language and source, so watch mode, the preview
+// server and archives don't run the tools again for diagrams that haven't changed
+var diagramCache sync.Map // map[[32]byte]string
+
+// codeBlockRenderer registers a function as the renderer for fenced code blocks
+type codeBlockRenderer renderer.NodeRendererFunc
+
+// RegisterFuncs implements renderer.NodeRenderer
+func (r codeBlockRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindFencedCodeBlock, renderer.NodeRendererFunc(r))
+}
+
+// diagramRenderer renders fenced code blocks tagged with a diagram language as inline SVG.
+// Every other fenced code block is passed on to fallback.
+type diagramRenderer struct {
+	fallback renderer.NodeRendererFunc
+}
+
+func (r *diagramRenderer) renderFencedCodeBlock(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	n := node.(*ast.FencedCodeBlock)
+	language := strings.ToLower(string(n.Language(source)))
+	engine, ok := diagramEngines[language]
+	if !ok {
+		return r.fallback(w, source, node, entering)
+	}
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+
+	code := linesValue(n.Lines(), source)
+	svg, err := renderDiagram(language, engine, code)
+	if errors.Is(err, exec.ErrNotFound) && engine.class != "" {
+		// Without the tool, leave the source unrendered in the markup client-side renderers
+		// (e.g. mermaid.js in a user template) look for
+		_, _ = w.WriteString("<pre class=\"" + engine.class + "\" title=\"" + htmlpkg.EscapeString(err.Error()) + "\">")
+		_, _ = w.WriteString(htmlpkg.EscapeString(string(code)))
+		_, _ = w.WriteString("</pre>\n")
+		return ast.WalkSkipChildren, nil
+	}
+	if err != nil {
+		// Show the source, so the document is still readable without the tool
+		_, _ = w.WriteString("<pre class=\"diagram-source\" title=\"" + htmlpkg.EscapeString(err.Error()) + "\"><code class=\"language-" + htmlpkg.EscapeString(language) + "\">")
+		_, _ = w.WriteString(htmlpkg.EscapeString(string(code)))
+		_, _ = w.WriteString("</code></pre>\n")
+		return ast.WalkSkipChildren, nil
+	}
+
+	_, _ = w.WriteString("<div class=\"diagram diagram-" + htmlpkg.EscapeString(language) + "\">")
+	_, _ = w.WriteString(svg)
+	_, _ = w.WriteString("</div>\n")
+	return ast.WalkSkipChildren, nil
+}
+
+// renderDiagram runs the engine for a diagram and returns the SVG, using the cache when possible
+func renderDiagram(language string, engine diagramEngine, code []byte) (string, error) {
+	key := sha256.Sum256(append([]byte(language+"\x00"), code...))
+	if svg, ok := diagramCache.Load(key); ok {
+		return svg.(string), nil
+	}
+
+	path, err := exec.LookPath(engine.command)
+	if err != nil {
+		return "", fmt.Errorf("%s diagrams need %s on the PATH: %w", language, engine.command, exec.ErrNotFound)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), diagramTimeout)
+	defer cancel()
+
+	args := engine.args
+	if engine.idFlag != "" {
+		args = append(append([]string{}, args...), engine.idFlag, fmt.Sprintf("diagram-%x", key[:6]))
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Stdin = bytes.NewReader(code)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return "", fmt.Errorf("%s timed out", engine.command)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s failed: %s", engine.command, msg)
+		}
+		return "", fmt.Errorf("%s failed: %w", engine.command, err)
+	}
+
+	svg, err := extractSVG(stdout.String())
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", engine.command, err)
+	}
+
+	diagramCache.Store(key, svg)
+	return svg, nil
+}
+
+// extractSVG returns the <svg> element of an SVG document, dropping the XML
+// declaration, doctype and comments that can't appear inside HTML
+func extractSVG(document string) (string, error) {
+	start := strings.Index(document, "<svg")
+	end := strings.LastIndex(document, "</svg>")
+	if start == -1 || end < start {
+		return "", errors.New("no SVG in output")
+	}
+	return document[start : end+len("</svg>")], nil
+}
+
+// renderPlainFencedCodeBlock renders a fenced code block the way goldmark's HTML renderer does
+func renderPlainFencedCodeBlock(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	n := node.(*ast.FencedCodeBlock)
+	if !entering {
+		_, _ = w.WriteString("</code></pre>\n")
+		return ast.WalkContinue, nil
+	}
+
+	_, _ = w.WriteString("<pre><code")
+	if language := n.Language(source); language != nil {
+		_, _ = w.WriteString(" class=\"language-")
+		html.DefaultWriter.Write(w, language)
+		_, _ = w.WriteString("\"")
+	}
+	_ = w.WriteByte('>')
+	for i := 0; i < n.Lines().Len(); i++ {
+		line := n.Lines().At(i)
+		html.DefaultWriter.RawWrite(w, line.Value(source))
+	}
+	return ast.WalkContinue, nil
+}
@@ -0,0 +1,182 @@
+package converter
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestDiagramHelperProcess stands in for a diagram tool when run by fakeDiagramEngine
+func TestDiagramHelperProcess(t *testing.T) {
+	if os.Getenv("MDVIEW_DIAGRAM_HELPER") != "1" {
+		return
+	}
+
+	input, _ := io.ReadAll(os.Stdin)
+	// The mode follows "--", before any id flag
+	mode := ""
+	for i, arg := range os.Args {
+		if arg == "--" && i+1 < len(os.Args) {
+			mode = os.Args[i+1]
+		}
+	}
+	switch mode {
+	case "svg":
+		fmt.Printf("<?xml version=\"1.0\"?>\n<!DOCTYPE svg>\n<!-- generated -->\n<svg xmlns=\"http://www.w3.org/2000/svg\"><text>%s</text></svg>\n",
+			strings.TrimSpace(string(input)))
+	case "fail":
+		fmt.Fprint(os.Stderr, "syntax error on line 1")
+		os.Exit(1)
+	case "empty":
+	}
+	os.Exit(0)
+}
+
+// fakeDiagramEngine replaces the tool for a diagram language with the test binary for the test
+func fakeDiagramEngine(t *testing.T, language, mode string) {
+	t.Helper()
+	t.Setenv("MDVIEW_DIAGRAM_HELPER", "1")
+
+	original, existed := diagramEngines[language]
+	diagramEngines[language] = diagramEngine{
+		command: os.Args[0],
+		args:    []string{"-test.run=^TestDiagramHelperProcess$", "--", mode},
+		idFlag:  original.idFlag,
+	}
+	t.Cleanup(func() {
+		if existed {
+			diagramEngines[language] = original
+		} else {
+			delete(diagramEngines, language)
+		}
+	})
+}
+
+func TestDiagram_RenderedAsInlineSVG(t *testing.T) {
+	fakeDiagramEngine(t, "dot", "svg")
+
+	c := newWithExtensions(t, "diagrams")
+	result := bodyOf(convert(t, c, "```dot\ndigraph { rendered }\n```\n"))
+
+	if !strings.Contains(result, `<div class="diagram diagram-dot"><svg xmlns="http://www.w3.org/2000/svg"><text>digraph { rendered }</text></svg></div>`) {
+		t.Errorf("expected inline SVG diagram, got:\n%s", result)
+	}
+	if strings.Contains(result, "<?xml") || strings.Contains(result, "<!DOCTYPE svg") {
+		t.Error("expected XML declaration and doctype to be stripped")
+	}
+	if strings.Contains(result, `class="language-dot"`) {
+		t.Error("expected diagram not to be rendered as code")
+	}
+}
+
+func TestDiagram_LanguageIsCaseInsensitive(t *testing.T) {
+	fakeDiagramEngine(t, "mermaid", "svg")
+
+	c := newWithExtensions(t, "diagrams")
+	result := bodyOf(convert(t, c, "```Mermaid\ngraph TD; case-->insensitive\n```\n"))
+
+	if !strings.Contains(result, `<div class="diagram diagram-mermaid"><svg`) {
+		t.Errorf("expected mermaid diagram, got:\n%s", result)
+	}
+}
+
+func TestDiagram_ToolFailureShowsSource(t *testing.T) {
+	fakeDiagramEngine(t, "plantuml", "fail")
+
+	c := newWithExtensions(t, "diagrams")
+	result := bodyOf(convert(t, c, "```plantuml\n@startuml\nA -> B\n@enduml\n```\n"))
+
+	if !strings.Contains(result, `<pre class="diagram-source"`) {
+		t.Fatalf("expected diagram source fallback, got:\n%s", result)
+	}
+	if !strings.Contains(result, "A -&gt; B") {
+		t.Error("expected escaped diagram source")
+	}
+	if !strings.Contains(result, "syntax error on line 1") {
+		t.Error("expected tool error as a tooltip")
+	}
+}
+
+func TestDiagram_NoSVGShowsSource(t *testing.T) {
+	fakeDiagramEngine(t, "dot", "empty")
+
+	c := newWithExtensions(t, "diagrams")
+	result := bodyOf(convert(t, c, "```dot\ndigraph { no -> output }\n```\n"))
+
+	if !strings.Contains(result, `<pre class="diagram-source"`) {
+		t.Errorf("expected diagram source fallback, got:\n%s", result)
+	}
+}
+
+func TestDiagram_MissingToolShowsSource(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+
+	c := newWithExtensions(t, "diagrams")
+	result := bodyOf(convert(t, c, "```plantuml\n@startuml\nA -> B\n@enduml\n```\n"))
+
+	if !strings.Contains(result, `<pre class="diagram-source" title="plantuml diagrams need plantuml on the PATH: `) {
+		t.Errorf("expected missing tool to be reported, got:\n%s", result)
+	}
+}
+
+func TestDiagram_MissingToolLeavesSourceUnrendered(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+
+	c := newWithExtensions(t, "diagrams")
+	result := bodyOf(convert(t, c, "```dot\ndigraph { missing -> tool }\n```\n\n```mermaid\ngraph TD; a-->b\n```\n"))
+
+	if !strings.Contains(result, `<pre class="graphviz" title="dot diagrams need dot on the PATH: `) ||
+		!strings.Contains(result, ">digraph { missing -&gt; tool }\n</pre>") {
+		t.Errorf("expected the dot source in a graphviz block, got:\n%s", result)
+	}
+	if !strings.Contains(result, `<pre class="mermaid" title="mermaid diagrams need mmdc on the PATH: `) ||
+		!strings.Contains(result, ">graph TD; a--&gt;b\n</pre>") {
+		t.Errorf("expected the mermaid source in a mermaid block, got:\n%s", result)
+	}
+	if strings.Contains(result, "<svg") {
+		t.Error("expected no diagram to be drawn")
+	}
+}
+
+func TestDiagram_OtherCodeUnchanged(t *testing.T) {
+	c := newWithExtensions(t, "diagrams")
+	result := bodyOf(convert(t, c, "```go\nx := \"<y>\"\n```\n\n```\nplain\n```\n"))
+
+	if !strings.Contains(result, "<pre><code class=\"language-go\">x := &quot;&lt;y&gt;&quot;\n</code></pre>") {
+		t.Errorf("expected regular code block, got:\n%s", result)
+	}
+	if !strings.Contains(result, "<pre><code>plain\n</code></pre>") {
+		t.Errorf("expected code block without language, got:\n%s", result)
+	}
+}
+
+func TestDiagram_WithHighlight(t *testing.T) {
+	fakeDiagramEngine(t, "dot", "svg")
+
+	c := newWithExtensions(t, "diagrams")
+	c.SetHighlight(true)
+	result := bodyOf(convert(t, c, "```dot\ndigraph { highlight -> on }\n```\n\n```go\nfunc f() {}\n```\n"))
+
+	if !strings.Contains(result, `<div class="diagram diagram-dot">`) {
+		t.Error("expected diagram to be rendered with highlighting enabled")
+	}
+	if !strings.Contains(result, `<code class="hljs language-go">`) {
+		t.Error("expected other code to be highlighted")
+	}
+}
+
+func TestExtractSVG(t *testing.T) {
+	got, err := extractSVG("<?xml version=\"1.0\"?>\n<svg a=\"1\"><g/></svg>\n<!-- trailing -->\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != `<svg a="1"><g/></svg>` {
+		t.Errorf("extractSVG() = %q", got)
+	}
+
+	if _, err := extractSVG("not svg"); err == nil {
+		t.Error("expected error for output without SVG")
+	}
+}
@@ -0,0 +1,81 @@
+package converter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Extensions selects the markdown extensions used for a conversion.
+// GitHub Flavored Markdown (tables, strikethrough, task lists, autolinks) is always enabled.
+type Extensions struct {
+	Footnotes       bool // [^1] references and footnote definitions
+	DefinitionLists bool // Term lines followed by ": definition" lines
+	CJK             bool // Line breaks and emphasis rules suited to Chinese, Japanese and Korean text
+	Typographer     bool // Smart quotes, dashes and ellipses
+	HardWraps       bool // Every line break in a paragraph becomes <br>
+	Math            bool // $...$ and $$...$$ math rendered to MathML
+	Alerts          bool // > [!NOTE] style callouts
+	Diagrams        bool // mermaid, dot and plantuml fences rendered to SVG
+}
+
+// DefaultExtensions returns the extensions enabled when none are configured
+func DefaultExtensions() Extensions {
+	return Extensions{
+		Typographer: true,
+		HardWraps:   true,
+		Alerts:      true,
+		Diagrams:    true,
+	}
+}
+
+// extensionNames maps the names accepted by ParseExtensions to the field they toggle
+var extensionNames = map[string]func(*Extensions) *bool{
+	"footnotes":   func(e *Extensions) *bool { return &e.Footnotes },
+	"deflist":     func(e *Extensions) *bool { return &e.DefinitionLists },
+	"cjk":         func(e *Extensions) *bool { return &e.CJK },
+	"typographer": func(e *Extensions) *bool { return &e.Typographer },
+	"hardwraps":   func(e *Extensions) *bool { return &e.HardWraps },
+	"math":        func(e *Extensions) *bool { return &e.Math },
+	"alerts":      func(e *Extensions) *bool { return &e.Alerts },
+	"diagrams":    func(e *Extensions) *bool { return &e.Diagrams },
+}
+
+// ExtensionNames returns the extension names accepted by ParseExtensions, sorted
+func ExtensionNames() []string {
+	names := make([]string, 0, len(extensionNames))
+	for name := range extensionNames {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseExtensions applies a comma-separated list of extension changes to base.
+// Each entry is an extension name, optionally prefixed with "+" (enable, the default)
+// or "-" (disable), e.g. "deflist,-hardwraps,+cjk". Names are case-insensitive.
+func ParseExtensions(spec string, base Extensions) (Extensions, error) {
+	result := base
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		enabled := true
+		switch entry[0] {
+		case '+':
+			entry = entry[1:]
+		case '-':
+			enabled = false
+			entry = entry[1:]
+		}
+
+		field, ok := extensionNames[strings.ToLower(strings.TrimSpace(entry))]
+		if !ok {
+			return base, fmt.Errorf("unknown extension %q (available: %s)", entry, strings.Join(ExtensionNames(), ", "))
+		}
+		*field(&result) = enabled
+	}
+	return result, nil
+}
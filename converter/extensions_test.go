@@ -0,0 +1,151 @@
+package converter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseExtensions(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		check   func(Extensions) bool
+		wantErr bool
+	}{
+		{
+			name:  "empty keeps defaults",
+			spec:  "",
+			check: func(e Extensions) bool { return e == DefaultExtensions() },
+		},
+		{
+			name:  "enable without prefix",
+			spec:  "deflist",
+			check: func(e Extensions) bool { return e.DefinitionLists },
+		},
+		{
+			name:  "enable and disable",
+			spec:  "+cjk, -hardwraps,-Typographer",
+			check: func(e Extensions) bool { return e.CJK && !e.HardWraps && !e.Typographer && !e.Footnotes },
+		},
+		{
+			name:  "later entries win",
+			spec:  "-math,math",
+			check: func(e Extensions) bool { return e.Math },
+		},
+		{
+			name:    "unknown extension",
+			spec:    "footnotes,emoji",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseExtensions(tt.spec, DefaultExtensions())
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error")
+				}
+				if got != DefaultExtensions() {
+					t.Error("expected base extensions to be returned on error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !tt.check(got) {
+				t.Errorf("unexpected extensions: %+v", got)
+			}
+		})
+	}
+}
+
+func TestExtensions_Footnotes(t *testing.T) {
+	c := New()
+	markdown := "Claim[^1].\n\n[^1]: Source.\n"
+
+	if result := bodyOf(convert(t, c, markdown)); strings.Contains(result, "footnote") {
+		t.Error("expected footnotes to be off by default")
+	}
+
+	ext := DefaultExtensions()
+	ext.Footnotes = true
+	c.SetExtensions(ext)
+	result := bodyOf(convert(t, c, markdown))
+	if !strings.Contains(result, `class="footnote-ref"`) || !strings.Contains(result, `class="footnotes"`) {
+		t.Errorf("expected footnotes when enabled, got:\n%s", result)
+	}
+}
+
+func TestExtensions_DefinitionLists(t *testing.T) {
+	c := New()
+	markdown := "Term\n: Definition\n"
+
+	if result := bodyOf(convert(t, c, markdown)); strings.Contains(result, "<dl>") {
+		t.Error("expected definition lists to be off by default")
+	}
+
+	ext := DefaultExtensions()
+	ext.DefinitionLists = true
+	c.SetExtensions(ext)
+	result := bodyOf(convert(t, c, markdown))
+	if !strings.Contains(result, "<dt>Term</dt>") || !strings.Contains(result, "<dd>Definition</dd>") {
+		t.Errorf("expected definition list, got:\n%s", result)
+	}
+}
+
+func TestExtensions_HardWraps(t *testing.T) {
+	c := New()
+	markdown := "Line one\nline two\n"
+
+	if result := bodyOf(convert(t, c, markdown)); !strings.Contains(result, "Line one<br />") {
+		t.Error("expected hard wraps by default")
+	}
+
+	ext := DefaultExtensions()
+	ext.HardWraps = false
+	c.SetExtensions(ext)
+	result := bodyOf(convert(t, c, markdown))
+	if strings.Contains(result, "<br") {
+		t.Error("expected soft line breaks with hard wraps off")
+	}
+	if !strings.Contains(result, "<p>Line one\nline two</p>") {
+		t.Errorf("expected lines in one paragraph, got:\n%s", result)
+	}
+}
+
+func TestExtensions_Typographer(t *testing.T) {
+	c := New()
+	ext := DefaultExtensions()
+	ext.Typographer = false
+	c.SetExtensions(ext)
+	result := bodyOf(convert(t, c, "\"quoted\" -- dash\n"))
+
+	if strings.Contains(result, "&ldquo;") || strings.Contains(result, "&ndash;") {
+		t.Errorf("expected plain punctuation with typographer off, got:\n%s", result)
+	}
+}
+
+func TestExtensions_AlertsAndDiagramsOnByDefault(t *testing.T) {
+	markdown := "Costs $x$ here.\n\n> [!NOTE]\n> Text\n\n```mermaid\ngraph TD; a-->b\n```\n"
+	result := bodyOf(convert(t, New(), markdown))
+
+	if strings.Contains(result, "<math") {
+		t.Error("expected no math by default")
+	}
+	if !strings.Contains(result, "markdown-alert") {
+		t.Error("expected alerts by default")
+	}
+	if strings.Contains(result, `<code class="language-mermaid">`) {
+		t.Errorf("expected diagrams by default, got:\n%s", result)
+	}
+
+	result = bodyOf(convert(t, newWithExtensions(t, "-alerts,-diagrams"), markdown))
+	if strings.Contains(result, "markdown-alert") {
+		t.Error("expected no alerts when disabled")
+	}
+	if !strings.Contains(result, `<code class="language-mermaid">`) {
+		t.Errorf("expected diagrams to stay code when disabled, got:\n%s", result)
+	}
+}
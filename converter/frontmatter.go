@@ -0,0 +1,158 @@
+package converter
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Metadata holds the fields parsed from a document's front matter
+type Metadata struct {
+	Title       string
+	Author      string
+	Date        string
+	Description string
+	Tags        []string
+	Fields      map[string]interface{} // Every front matter field, including the ones above
+}
+
+// utf8BOM is stripped before looking for a front matter delimiter
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// splitFrontMatter separates a leading front matter block from the markdown body.
+// YAML front matter is delimited by "---" lines (closed by "---" or "..."),
+// TOML front matter by "+++" lines. Returns the format ("yaml" or "toml"), the raw
+// block and the remaining body; format is "" if the source has no front matter.
+func splitFrontMatter(source []byte) (format string, block []byte, body []byte) {
+	src := bytes.TrimPrefix(source, utf8BOM)
+
+	firstLine, rest, found := bytes.Cut(src, []byte("\n"))
+	if !found {
+		return "", nil, source
+	}
+
+	var closers []string
+	switch string(bytes.TrimRight(firstLine, " \t\r")) {
+	case "---":
+		format, closers = "yaml", []string{"---", "..."}
+	case "+++":
+		format, closers = "toml", []string{"+++"}
+	default:
+		return "", nil, source
+	}
+
+	// Find the closing delimiter line
+	offset := 0
+	for offset <= len(rest) {
+		lineEnd := bytes.IndexByte(rest[offset:], '\n')
+		var line []byte
+		next := len(rest) + 1
+		if lineEnd == -1 {
+			line = rest[offset:]
+		} else {
+			line = rest[offset : offset+lineEnd]
+			next = offset + lineEnd + 1
+		}
+
+		trimmed := string(bytes.TrimRight(line, " \t\r"))
+		for _, closer := range closers {
+			if trimmed == closer {
+				if next > len(rest) {
+					return format, rest[:offset], nil
+				}
+				return format, rest[:offset], rest[next:]
+			}
+		}
+		offset = next
+	}
+
+	// Unterminated block - not front matter
+	return "", nil, source
+}
+
+// parseFrontMatter extracts front matter from source.
+// Returns the metadata (nil if there is none) and the markdown body without it.
+// A block that fails to parse is left in the body so nothing is silently dropped.
+func parseFrontMatter(source []byte) (*Metadata, []byte) {
+	format, block, body := splitFrontMatter(source)
+	if format == "" {
+		return nil, source
+	}
+
+	fields := make(map[string]interface{})
+	var err error
+	switch format {
+	case "yaml":
+		err = yaml.Unmarshal(block, &fields)
+	case "toml":
+		err = toml.Unmarshal(block, &fields)
+	}
+	if err != nil {
+		return nil, source
+	}
+
+	// An empty YAML document unmarshals to a nil map
+	if fields == nil {
+		fields = make(map[string]interface{})
+	}
+
+	meta := &Metadata{
+		Title:       metadataString(fields["title"]),
+		Author:      metadataString(fields["author"]),
+		Date:        metadataString(fields["date"]),
+		Description: metadataString(fields["description"]),
+		Tags:        metadataList(fields["tags"]),
+		Fields:      fields,
+	}
+	return meta, body
+}
+
+// metadataString formats a scalar front matter value for display
+func metadataString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return strings.TrimSpace(v)
+	case time.Time:
+		// Dates without a time of day are shown without one
+		if v.Hour() == 0 && v.Minute() == 0 && v.Second() == 0 && v.Nanosecond() == 0 {
+			return v.Format("2006-01-02")
+		}
+		return v.Format(time.RFC3339)
+	case []interface{}:
+		return strings.Join(metadataList(v), ", ")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// metadataList converts a list value, or a comma-separated string, to a list of strings
+func metadataList(value interface{}) []string {
+	var items []string
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		for _, item := range v {
+			if s := metadataString(item); s != "" {
+				items = append(items, s)
+			}
+		}
+	case string:
+		for _, item := range strings.Split(v, ",") {
+			if s := strings.TrimSpace(item); s != "" {
+				items = append(items, s)
+			}
+		}
+	default:
+		if s := metadataString(v); s != "" {
+			items = append(items, s)
+		}
+	}
+	return items
+}
@@ -0,0 +1,254 @@
+package converter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitFrontMatter(t *testing.T) {
+	tests := []struct {
+		name       string
+		source     string
+		wantFormat string
+		wantBlock  string
+		wantBody   string
+	}{
+		{
+			name:       "yaml",
+			source:     "---\ntitle: Hello\n---\n# Body\n",
+			wantFormat: "yaml",
+			wantBlock:  "title: Hello\n",
+			wantBody:   "# Body\n",
+		},
+		{
+			name:       "yaml closed with dots",
+			source:     "---\ntitle: Hello\n...\n# Body\n",
+			wantFormat: "yaml",
+			wantBlock:  "title: Hello\n",
+			wantBody:   "# Body\n",
+		},
+		{
+			name:       "toml",
+			source:     "+++\ntitle = \"Hello\"\n+++\n# Body\n",
+			wantFormat: "toml",
+			wantBlock:  "title = \"Hello\"\n",
+			wantBody:   "# Body\n",
+		},
+		{
+			name:       "windows line endings",
+			source:     "---\r\ntitle: Hello\r\n---\r\n# Body\r\n",
+			wantFormat: "yaml",
+			wantBlock:  "title: Hello\r\n",
+			wantBody:   "# Body\r\n",
+		},
+		{
+			name:       "byte order mark",
+			source:     "\xEF\xBB\xBF---\ntitle: Hello\n---\n# Body\n",
+			wantFormat: "yaml",
+			wantBlock:  "title: Hello\n",
+			wantBody:   "# Body\n",
+		},
+		{
+			name:       "closing delimiter at end of file",
+			source:     "---\ntitle: Hello\n---",
+			wantFormat: "yaml",
+			wantBlock:  "title: Hello\n",
+			wantBody:   "",
+		},
+		{
+			name:       "no front matter",
+			source:     "# Body\n\n---\n\nMore\n",
+			wantFormat: "",
+			wantBody:   "# Body\n\n---\n\nMore\n",
+		},
+		{
+			name:       "unterminated block",
+			source:     "---\ntitle: Hello\n# Body\n",
+			wantFormat: "",
+			wantBody:   "---\ntitle: Hello\n# Body\n",
+		},
+		{
+			name:       "delimiter must be alone on the line",
+			source:     "--- not front matter\n---\n",
+			wantFormat: "",
+			wantBody:   "--- not front matter\n---\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, block, body := splitFrontMatter([]byte(tt.source))
+			if format != tt.wantFormat {
+				t.Errorf("format = %q, want %q", format, tt.wantFormat)
+			}
+			if string(block) != tt.wantBlock {
+				t.Errorf("block = %q, want %q", block, tt.wantBlock)
+			}
+			if string(body) != tt.wantBody {
+				t.Errorf("body = %q, want %q", body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestParseFrontMatter_YAMLFields(t *testing.T) {
+	source := `---
+title: Design Doc
+author: Jane Doe
+date: 2026-01-15
+description: How the thing works
+tags: [design, architecture]
+draft: true
+---
+# Body
+`
+	meta, body := parseFrontMatter([]byte(source))
+	if meta == nil {
+		t.Fatal("expected metadata")
+	}
+
+	if meta.Title != "Design Doc" {
+		t.Errorf("Title = %q", meta.Title)
+	}
+	if meta.Author != "Jane Doe" {
+		t.Errorf("Author = %q", meta.Author)
+	}
+	if meta.Date != "2026-01-15" {
+		t.Errorf("Date = %q", meta.Date)
+	}
+	if meta.Description != "How the thing works" {
+		t.Errorf("Description = %q", meta.Description)
+	}
+	if strings.Join(meta.Tags, ",") != "design,architecture" {
+		t.Errorf("Tags = %v", meta.Tags)
+	}
+	if meta.Fields["draft"] != true {
+		t.Errorf("expected custom fields to be kept, got %v", meta.Fields)
+	}
+	if string(body) != "# Body\n" {
+		t.Errorf("body = %q", body)
+	}
+}
+
+func TestParseFrontMatter_TOMLFields(t *testing.T) {
+	source := `+++
+title = "Design Doc"
+tags = ["design", "architecture"]
+date = 2026-01-15
++++
+# Body
+`
+	meta, body := parseFrontMatter([]byte(source))
+	if meta == nil {
+		t.Fatal("expected metadata")
+	}
+
+	if meta.Title != "Design Doc" {
+		t.Errorf("Title = %q", meta.Title)
+	}
+	if meta.Date != "2026-01-15" {
+		t.Errorf("Date = %q", meta.Date)
+	}
+	if len(meta.Tags) != 2 {
+		t.Errorf("Tags = %v", meta.Tags)
+	}
+	if string(body) != "# Body\n" {
+		t.Errorf("body = %q", body)
+	}
+}
+
+func TestParseFrontMatter_CommaSeparatedTags(t *testing.T) {
+	meta, _ := parseFrontMatter([]byte("---\ntags: go, markdown ,  html\n---\n"))
+	if meta == nil {
+		t.Fatal("expected metadata")
+	}
+	if strings.Join(meta.Tags, "|") != "go|markdown|html" {
+		t.Errorf("Tags = %v", meta.Tags)
+	}
+}
+
+func TestParseFrontMatter_InvalidYAMLKeptInBody(t *testing.T) {
+	source := "---\ntitle: [unclosed\n---\n# Body\n"
+
+	meta, body := parseFrontMatter([]byte(source))
+	if meta != nil {
+		t.Errorf("expected no metadata for invalid YAML, got %+v", meta)
+	}
+	if string(body) != source {
+		t.Errorf("expected source to be returned unchanged, got %q", body)
+	}
+}
+
+func TestFrontMatter_StrippedFromOutput(t *testing.T) {
+	c := New()
+	result := convert(t, c, "---\ntitle: Hello\nauthor: Jane\n---\n# Heading\n")
+
+	if strings.Contains(result, "<hr") {
+		t.Error("expected front matter not to render as a horizontal rule")
+	}
+	if strings.Contains(result, "author: Jane") {
+		t.Error("expected front matter fields not to render as text")
+	}
+	if !strings.Contains(result, `<h1 id="heading">Heading</h1>`) {
+		t.Error("expected body to be rendered")
+	}
+}
+
+func TestFrontMatter_TitleOverridesSetTitle(t *testing.T) {
+	c := New()
+	c.SetTitle("from-filename")
+
+	result := convert(t, c, "---\ntitle: From Front Matter\n---\n# Body\n")
+
+	if !strings.Contains(result, "<title>From Front Matter</title>") {
+		t.Error("expected front matter title in output")
+	}
+	if strings.Contains(result, "from-filename") {
+		t.Error("expected SetTitle value to be overridden")
+	}
+}
+
+func TestFrontMatter_MetaTags(t *testing.T) {
+	c := New()
+	result := convert(t, c, `---
+author: Jane "JD" Doe
+description: A <short> summary
+date: 2026-01-15
+tags:
+  - one
+  - two
+---
+# Body
+`)
+
+	wants := []string{
+		`<meta name="author" content="Jane &#34;JD&#34; Doe">`,
+		`<meta name="description" content="A &lt;short&gt; summary">`,
+		`<meta name="date" content="2026-01-15">`,
+		`<meta name="keywords" content="one, two">`,
+	}
+	for _, want := range wants {
+		if !strings.Contains(result, want) {
+			t.Errorf("expected %q in output", want)
+		}
+	}
+
+	// Meta tags belong in the head
+	if strings.Index(result, `<meta name="author"`) > strings.Index(result, "</head>") {
+		t.Error("expected meta tags inside <head>")
+	}
+}
+
+func TestFrontMatter_MetadataAccessor(t *testing.T) {
+	c := New()
+
+	convert(t, c, "---\ntitle: First\n---\n# Body\n")
+	if c.Metadata() == nil || c.Metadata().Title != "First" {
+		t.Errorf("expected metadata from first conversion, got %+v", c.Metadata())
+	}
+
+	convert(t, c, "# No front matter\n")
+	if c.Metadata() != nil {
+		t.Error("expected metadata to be cleared for a document without front matter")
+	}
+}
@@ -0,0 +1,125 @@
+package converter
+
+import (
+	"bytes"
+	htmlpkg "html"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/util"
+)
+
+// hljsClasses maps chroma token types to the highlight.js class names styled by the
+// templates. Token types without an entry fall back to their parent type.
+var hljsClasses = map[chroma.TokenType]string{
+	chroma.Comment:        "hljs-comment",
+	chroma.CommentPreproc: "hljs-meta",
+
+	chroma.Keyword:         "hljs-keyword",
+	chroma.KeywordType:     "hljs-type",
+	chroma.KeywordConstant: "hljs-literal",
+
+	chroma.NameFunction:      "hljs-title function_",
+	chroma.NameFunctionMagic: "hljs-title function_",
+	chroma.NameClass:         "hljs-title class_",
+	chroma.NameException:     "hljs-title class_",
+	chroma.NameBuiltin:       "hljs-built_in",
+	chroma.NameBuiltinPseudo: "hljs-built_in",
+	chroma.NameVariable:      "hljs-variable",
+	chroma.NameConstant:      "hljs-variable",
+	chroma.NameTag:           "hljs-name",
+	chroma.NameAttribute:     "hljs-attr",
+	chroma.NameDecorator:     "hljs-meta",
+	chroma.NameLabel:         "hljs-symbol",
+	chroma.NameEntity:        "hljs-symbol",
+
+	chroma.LiteralString:         "hljs-string",
+	chroma.LiteralStringRegex:    "hljs-regexp",
+	chroma.LiteralStringSymbol:   "hljs-symbol",
+	chroma.LiteralStringInterpol: "hljs-subst",
+	chroma.LiteralNumber:         "hljs-number",
+	chroma.LiteralDate:           "hljs-number",
+
+	chroma.Operator:     "hljs-operator",
+	chroma.OperatorWord: "hljs-keyword",
+	chroma.Punctuation:  "hljs-punctuation",
+
+	chroma.GenericDeleted:    "hljs-deletion",
+	chroma.GenericInserted:   "hljs-addition",
+	chroma.GenericHeading:    "hljs-section",
+	chroma.GenericSubheading: "hljs-section",
+	chroma.GenericEmph:       "hljs-emphasis",
+	chroma.GenericStrong:     "hljs-strong",
+}
+
+// hljsClass returns the highlight.js class for a token type, or "" for plain text
+func hljsClass(tokenType chroma.TokenType) string {
+	for t := tokenType; t > 0; t = t.Parent() {
+		if class, ok := hljsClasses[t]; ok {
+			return class
+		}
+	}
+	return ""
+}
+
+// highlightRenderer renders fenced code blocks with syntax highlighting at conversion
+// time, producing the same markup highlight.js would so the template theme applies
+// without any JavaScript.
+type highlightRenderer struct{}
+
+func (r *highlightRenderer) renderFencedCodeBlock(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+
+	n := node.(*ast.FencedCodeBlock)
+	language := string(n.Language(source))
+	code := string(linesValue(n.Lines(), source))
+
+	_, _ = w.WriteString("<pre><code class=\"hljs")
+	if language != "" {
+		_, _ = w.WriteString(" language-" + htmlpkg.EscapeString(language))
+	}
+	_, _ = w.WriteString("\">")
+	_, _ = w.WriteString(highlightCode(code, language))
+	_, _ = w.WriteString("</code></pre>\n")
+
+	return ast.WalkSkipChildren, nil
+}
+
+// highlightCode returns code as HTML with highlight.js class spans.
+// Code in an unknown (or no) language is only escaped.
+func highlightCode(code, language string) string {
+	if language == "" {
+		return htmlpkg.EscapeString(code)
+	}
+
+	lexer := lexers.Get(language)
+	if lexer == nil {
+		return htmlpkg.EscapeString(code)
+	}
+
+	iterator, err := chroma.Coalesce(lexer).Tokenise(nil, code)
+	if err != nil {
+		return htmlpkg.EscapeString(code)
+	}
+
+	var buf bytes.Buffer
+	for _, token := range iterator.Tokens() {
+		class := hljsClass(token.Type)
+		// Spans never cross line breaks, so every line can be styled on its own
+		for i, line := range strings.Split(token.Value, "\n") {
+			if i > 0 {
+				buf.WriteByte('\n')
+			}
+			if class == "" || strings.TrimSpace(line) == "" {
+				buf.WriteString(htmlpkg.EscapeString(line))
+				continue
+			}
+			buf.WriteString("<span class=\"" + class + "\">" + htmlpkg.EscapeString(line) + "</span>")
+		}
+	}
+	return buf.String()
+}
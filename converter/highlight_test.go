@@ -0,0 +1,145 @@
+package converter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/chroma/v2"
+
+	"mdview/templates"
+)
+
+func TestHighlight_GoCode(t *testing.T) {
+	c := New()
+	c.SetHighlight(true)
+	result := bodyOf(convert(t, c, "```go\n// Greet says hi\nfunc greet() string {\n\treturn \"hi\"\n}\n```\n"))
+
+	wants := []string{
+		`<pre><code class="hljs language-go">`,
+		`<span class="hljs-comment">// Greet says hi</span>`,
+		`<span class="hljs-keyword">func</span>`,
+		`<span class="hljs-title function_">greet</span>`,
+		`<span class="hljs-type">string</span>`,
+		`<span class="hljs-string">&#34;hi&#34;</span>`,
+	}
+	for _, want := range wants {
+		if !strings.Contains(result, want) {
+			t.Errorf("expected %q in output, got:\n%s", want, result)
+		}
+	}
+}
+
+func TestHighlight_EscapesCode(t *testing.T) {
+	c := New()
+	c.SetHighlight(true)
+	result := bodyOf(convert(t, c, "```html\n<script>alert(1)</script>\n```\n"))
+
+	if strings.Contains(result, "<script>alert") {
+		t.Error("expected code to be escaped")
+	}
+	if !strings.Contains(result, `<span class="hljs-name">script</span>`) {
+		t.Errorf("expected tag names to be highlighted, got:\n%s", result)
+	}
+}
+
+func TestHighlight_UnknownLanguage(t *testing.T) {
+	c := New()
+	c.SetHighlight(true)
+	result := bodyOf(convert(t, c, "```nosuchlang\na < b\n```\n"))
+
+	if !strings.Contains(result, `<pre><code class="hljs language-nosuchlang">a &lt; b`) {
+		t.Errorf("expected unknown language to be escaped without spans, got:\n%s", result)
+	}
+}
+
+func TestHighlight_NoLanguage(t *testing.T) {
+	c := New()
+	c.SetHighlight(true)
+	result := bodyOf(convert(t, c, "```\nplain text\n```\n"))
+
+	if !strings.Contains(result, `<pre><code class="hljs">plain text`) {
+		t.Errorf("expected plain code block, got:\n%s", result)
+	}
+}
+
+func TestHighlight_DropsHighlightJS(t *testing.T) {
+	c := New()
+	c.SetHighlight(true)
+	result := convert(t, c, "# Test\n")
+
+	if strings.Contains(result, "hljs.highlightAll") {
+		t.Error("expected highlight.js to be left out of the output")
+	}
+	// The theme is still needed for the server-side classes
+	if !strings.Contains(result, ".hljs-keyword") {
+		t.Error("expected template CSS to keep the hljs theme")
+	}
+}
+
+func TestHighlight_KeepsTemplateJS(t *testing.T) {
+	dir := t.TempDir()
+	templateDir := filepath.Join(dir, "scripted")
+	if err := os.MkdirAll(templateDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"template.js":  "window.templateScript = true;",
+		"highlight.js": "window.highlighter = true;",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(templateDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	previous := templates.Dirs()
+	templates.SetDirs(dir)
+	t.Cleanup(func() { templates.SetDirs(previous...) })
+
+	c := New()
+	c.SetHighlight(true)
+	result, err := convertWithTemplate(t, c, "# Test\n", "scripted")
+	if err != nil {
+		t.Fatalf("Convert() error = %v", err)
+	}
+	if !strings.Contains(result, "window.templateScript = true;") {
+		t.Error("expected template.js to be kept with highlighting enabled")
+	}
+	if strings.Contains(result, "window.highlighter") {
+		t.Error("expected the template's highlight.js to be left out")
+	}
+}
+
+func TestHighlight_DisabledByDefault(t *testing.T) {
+	c := New()
+	result := convert(t, c, "```go\nfunc main() {}\n```\n")
+
+	if !strings.Contains(result, `<code class="language-go">func main() {}`) {
+		t.Error("expected fenced code to be left for highlight.js by default")
+	}
+	if !strings.Contains(result, "hljs.highlightAll") {
+		t.Error("expected highlight.js in the output by default")
+	}
+}
+
+func TestHLJSClass_FallsBackToParent(t *testing.T) {
+	tests := []struct {
+		tokenType chroma.TokenType
+		want      string
+	}{
+		{chroma.CommentSingle, "hljs-comment"},
+		{chroma.CommentPreprocFile, "hljs-meta"},
+		{chroma.KeywordDeclaration, "hljs-keyword"},
+		{chroma.LiteralStringDouble, "hljs-string"},
+		{chroma.LiteralNumberHex, "hljs-number"},
+		{chroma.Name, ""},
+		{chroma.Text, ""},
+	}
+
+	for _, tt := range tests {
+		if got := hljsClass(tt.tokenType); got != tt.want {
+			t.Errorf("hljsClass(%v) = %q, want %q", tt.tokenType, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package converter
+
+import (
+	"fmt"
+	htmlpkg "html"
+	"html/template"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"mdview/templates"
+)
+
+// PageData is the data a template's layout.html is executed with.
+// The HTML fields are complete elements and are inserted as is.
+type PageData struct {
+	Title     string        // Page title: the front matter title, or the one set with SetTitle
+	Content   template.HTML // The rendered markdown
+	TOC       template.HTML // Table of contents sidebar (<nav>), empty unless the sidebar is enabled
+	Meta      template.HTML // <meta> tags for the front matter fields
+	Head      template.HTML // The template's template.html with the title applied, plus a <title> if it has none
+	CSS       template.HTML // <style> element with the template CSS
+	Scripts   template.HTML // <script> elements for highlight.js, the template JS and live reload
+	Metadata  Metadata      // Front matter fields (zero if the document has none)
+	Generated time.Time     // Time of the conversion
+	Source    string        // Path of the markdown file (see SetSourcePath), empty if unknown
+}
+
+// cachedLayout is a parsed layout and the source it was parsed from
+type cachedLayout struct {
+	source string
+	layout *template.Template
+}
+
+// layoutCache holds the last parsed layout of each template, keyed by template name,
+// so it holds one entry per template however often a layout is edited
+var layoutCache sync.Map // map[string]cachedLayout
+
+// parseLayout parses the layout of the named template, reusing the result while its
+// source stays the same and replacing it when the source changes
+func parseLayout(templateName, source string) (*template.Template, error) {
+	if cached, ok := layoutCache.Load(templateName); ok && cached.(cachedLayout).source == source {
+		return cached.(cachedLayout).layout, nil
+	}
+
+	layout, err := template.New("layout.html").Parse(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse layout: %w", err)
+	}
+	layoutCache.Store(templateName, cachedLayout{source: source, layout: layout})
+	return layout, nil
+}
+
+// writePage writes the HTML document: the layout of the named template executed around
+// the rendered content
+func (c *Converter) writePage(w io.Writer, templateName string, tmpl *templates.Template, content string) error {
+	layout, err := parseLayout(templateName, tmpl.Layout)
+	if err != nil {
+		return err
+	}
+
+	// Front matter title takes precedence over the one set with SetTitle
+	title := c.title
+	if c.metadata != nil && c.metadata.Title != "" {
+		title = c.metadata.Title
+	}
+
+	data := PageData{
+		Title:     title,
+		Content:   template.HTML(content),
+		Meta:      template.HTML(c.metaTags()),
+		Head:      template.HTML(headHTML(tmpl, title)),
+		CSS:       template.HTML(c.styleHTML(tmpl)),
+		Scripts:   template.HTML(c.scriptsHTML(tmpl)),
+		Generated: time.Now(),
+		Source:    c.sourcePath,
+	}
+	if c.tocSidebar {
+		data.TOC = template.HTML(renderTOCNav(filterHeadings(c.headings, c.tocDepth), "toc toc-sidebar"))
+	}
+	if c.metadata != nil {
+		data.Metadata = *c.metadata
+	}
+
+	if err := layout.Execute(w, data); err != nil {
+		return fmt.Errorf("failed to execute layout: %w", err)
+	}
+	return nil
+}
+
+// headHTML returns the template's extra <head> markup with its <title> replaced by title,
+// followed by a <title> element if the markup has none to replace
+func headHTML(tmpl *templates.Template, title string) string {
+	var sb strings.Builder
+	titleWritten := false
+
+	if tmpl.HTML != "" {
+		templateHTML := tmpl.HTML
+		// Replace title if custom title is set
+		if title != "" && titlePattern.MatchString(templateHTML) {
+			newTitle := "<title>" + htmlpkg.EscapeString(title) + "</title>"
+			templateHTML = titlePattern.ReplaceAllString(templateHTML, newTitle)
+			titleWritten = true
+		}
+		sb.WriteString(templateHTML)
+		sb.WriteString("\n")
+	}
+
+	// Template has no <title> to replace, so write one
+	if title != "" && !titleWritten {
+		sb.WriteString("<title>" + htmlpkg.EscapeString(title) + "</title>\n")
+	}
+
+	return sb.String()
+}
+
+// metaTags returns <meta> tags for the front matter fields of the document
+func (c *Converter) metaTags() string {
+	if c.metadata == nil {
+		return ""
+	}
+
+	tags := []struct {
+		name    string
+		content string
+	}{
+		{"author", c.metadata.Author},
+		{"description", c.metadata.Description},
+		{"date", c.metadata.Date},
+		{"keywords", strings.Join(c.metadata.Tags, ", ")},
+	}
+
+	var sb strings.Builder
+	for _, tag := range tags {
+		if tag.content == "" {
+			continue
+		}
+		fmt.Fprintf(&sb, "<meta name=\"%s\" content=\"%s\">\n", tag.name, htmlpkg.EscapeString(tag.content))
+	}
+	return sb.String()
+}
+
+// styleHTML returns a <style> element with the template CSS, or "" if it has none
+func (c *Converter) styleHTML(tmpl *templates.Template) string {
+	if tmpl.CSS == "" {
+		return ""
+	}
+
+	css := tmpl.CSS
+	if c.selfContained && c.baseDir != "" {
+		css = c.embedCSSAssets(css)
+	}
+	return "<style>\n" + css + "\n</style>\n"
+}
+
+// scriptsHTML returns the <script> elements for highlight.js, the template JS and live reload
+func (c *Converter) scriptsHTML(tmpl *templates.Template) string {
+	var sb strings.Builder
+
+	// Code is already highlighted, so highlight.js isn't needed
+	if tmpl.Highlight != "" && !c.highlight {
+		sb.WriteString("<script>\n" + tmpl.Highlight + "\n</script>\n")
+	}
+	if tmpl.JS != "" {
+		sb.WriteString("<script>\n" + tmpl.JS + "\n</script>\n")
+	}
+
+	if c.liveReloadSrc != "" {
+		config := fmt.Sprintf("window.mdviewLiveReload = {src: %s, token: %s};\n",
+			strconv.Quote(c.liveReloadSrc), strconv.Quote(c.liveReloadToken))
+		sb.WriteString("<script>\n" + config + liveReloadJS + "</script>\n")
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,150 @@
+package converter
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"mdview/templates"
+)
+
+// useLayoutTemplate creates a user template named "layout-test" with the given
+// layout.html and CSS, and makes it available for the duration of a test
+func useLayoutTemplate(t *testing.T, layout string) {
+	t.Helper()
+	dir := t.TempDir()
+	templateDir := filepath.Join(dir, "layout-test")
+	if err := os.MkdirAll(templateDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(templateDir, "layout.html"), []byte(layout), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(templateDir, "template.css"), []byte("body { margin: 0; }"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	previous := templates.Dirs()
+	templates.SetDirs(dir)
+	t.Cleanup(func() { templates.SetDirs(previous...) })
+}
+
+func convertWithTemplate(t *testing.T, c *Converter, markdown, templateName string) (string, error) {
+	t.Helper()
+	var buf bytes.Buffer
+	err := c.Convert(strings.NewReader(markdown), &buf, templateName)
+	return buf.String(), err
+}
+
+func TestLayout_DefaultSkeleton(t *testing.T) {
+	c := New()
+	result := convert(t, c, "# Hello\n")
+
+	if !strings.HasPrefix(result, "<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n") {
+		t.Errorf("expected document to start with the doctype and head, got:\n%.100s", result)
+	}
+	if !strings.Contains(result, "</head>\n<body>\n<article class=\"markdown-body\">\n<h1 id=\"hello\">Hello</h1>\n\n</article>\n") {
+		t.Error("expected the content inside the article")
+	}
+	if !strings.HasSuffix(result, "</body>\n</html>\n") {
+		t.Error("expected document to end with </html>")
+	}
+}
+
+func TestLayout_Placeholders(t *testing.T) {
+	useLayoutTemplate(t, `<!DOCTYPE html>
+<html>
+<head>{{.Meta}}{{.Head}}{{.CSS}}</head>
+<body>
+<header class="brand">{{.Title}} by {{.Metadata.Author}}</header>
+<aside>{{.TOC}}</aside>
+<main><article class="markdown-body">{{.Content}}</article></main>
+<footer>Generated {{.Generated.Format "2006"}} from {{.Source}}</footer>
+{{.Scripts}}</body>
+</html>
+`)
+
+	c := New()
+	c.SetTOCSidebar(true)
+	c.SetSourcePath("/docs/guide.md")
+	c.SetLiveReload("out.reload.js", "token")
+	result, err := convertWithTemplate(t, c, "---\ntitle: Guide <1>\nauthor: Ann\n---\n# Intro\n\nText\n", "layout-test")
+	if err != nil {
+		t.Fatalf("conversion failed: %v", err)
+	}
+
+	wants := []string{
+		`<meta name="author" content="Ann">`,
+		"<title>Guide &lt;1&gt;</title>",
+		"<style>\nbody { margin: 0; }\n</style>",
+		`<header class="brand">Guide &lt;1&gt; by Ann</header>`,
+		`<aside><nav class="toc toc-sidebar">`,
+		`<main><article class="markdown-body"><h1 id="intro">Intro</h1>`,
+		"<footer>Generated " + time.Now().Format("2006") + " from /docs/guide.md</footer>",
+		"window.mdviewLiveReload",
+	}
+	for _, want := range wants {
+		if !strings.Contains(result, want) {
+			t.Errorf("expected %q in output, got:\n%s", want, result)
+		}
+	}
+}
+
+func TestLayout_EmptyFieldsWithoutFrontMatter(t *testing.T) {
+	useLayoutTemplate(t, `<p>[{{.Title}}|{{.Metadata.Author}}|{{.TOC}}|{{.Source}}]</p>{{.Content}}`)
+
+	c := New()
+	result, err := convertWithTemplate(t, c, "Text\n", "layout-test")
+	if err != nil {
+		t.Fatalf("conversion failed: %v", err)
+	}
+	if !strings.HasPrefix(result, "<p>[|||]</p><p>Text</p>") {
+		t.Errorf("expected empty fields, got:\n%s", result)
+	}
+}
+
+func TestLayout_EditedLayoutReplacesCachedOne(t *testing.T) {
+	c := New()
+	for _, layout := range []string{`<main>first {{.Content}}</main>`, `<main>second {{.Content}}</main>`} {
+		useLayoutTemplate(t, layout)
+		result, err := convertWithTemplate(t, c, "Text\n", "layout-test")
+		if err != nil {
+			t.Fatalf("Convert failed: %v", err)
+		}
+		if want := strings.SplitN(layout, " ", 2)[0]; !strings.HasPrefix(result, want) {
+			t.Errorf("expected the current layout %q, got:\n%s", want, result)
+		}
+	}
+
+	entries := 0
+	layoutCache.Range(func(name, _ any) bool {
+		if name == "layout-test" {
+			entries++
+		}
+		return true
+	})
+	if entries != 1 {
+		t.Errorf("expected one cached layout for the template, got %d", entries)
+	}
+}
+
+func TestLayout_ParseError(t *testing.T) {
+	useLayoutTemplate(t, `<body>{{.Content</body>`)
+
+	c := New()
+	if _, err := convertWithTemplate(t, c, "Text\n", "layout-test"); err == nil || !strings.Contains(err.Error(), "layout") {
+		t.Errorf("expected a layout parse error, got: %v", err)
+	}
+}
+
+func TestLayout_ExecuteError(t *testing.T) {
+	useLayoutTemplate(t, `<body>{{.Missing}}</body>`)
+
+	c := New()
+	if _, err := convertWithTemplate(t, c, "Text\n", "layout-test"); err == nil || !strings.Contains(err.Error(), "layout") {
+		t.Errorf("expected a layout execution error, got: %v", err)
+	}
+}
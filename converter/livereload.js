@@ -0,0 +1,29 @@
+// mdview live reload
+// Polls the sidecar script written by `mdview --watch` and reloads the page
+// when the output has been regenerated. Works over file:// because it loads
+// the sidecar as a <script> rather than fetching it.
+
+(function() {
+  'use strict';
+
+  var config = window.mdviewLiveReload;
+  if (!config || !config.src) return;
+
+  // Called by the sidecar script with the token of the latest build
+  window.mdviewLiveReloadCheck = function(token) {
+    if (token && token !== config.token) {
+      window.location.reload();
+    }
+  };
+
+  function poll() {
+    var script = document.createElement('script');
+    script.src = config.src + '?t=' + Date.now();
+    script.onload = script.onerror = function() {
+      script.parentNode.removeChild(script);
+    };
+    document.head.appendChild(script);
+  }
+
+  setInterval(poll, config.interval || 1000);
+})();
@@ -0,0 +1,308 @@
+package converter
+
+import (
+	"bytes"
+	htmlpkg "html"
+	"strings"
+
+	"github.com/wyatt915/treeblood"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// AST node kinds for LaTeX math
+var (
+	kindMathInline = ast.NewNodeKind("MathInline")
+	kindMathBlock  = ast.NewNodeKind("MathBlock")
+)
+
+// mathInline is $...$ (or $$...$$ inside a paragraph) math within a line of text
+type mathInline struct {
+	ast.BaseInline
+	tex     string
+	display bool // Delimited by $$, rendered in display style
+}
+
+// Kind implements ast.Node
+func (n *mathInline) Kind() ast.NodeKind {
+	return kindMathInline
+}
+
+// Dump implements ast.Node
+func (n *mathInline) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"TeX": n.tex}, nil)
+}
+
+// mathBlock is display math in a $$ ... $$ block of its own
+type mathBlock struct {
+	ast.BaseBlock
+	closed bool // The closing $$ has been read
+}
+
+// Kind implements ast.Node
+func (n *mathBlock) Kind() ast.NodeKind {
+	return kindMathBlock
+}
+
+// IsRaw implements ast.Node, so the TeX is not parsed as markdown
+func (n *mathBlock) IsRaw() bool {
+	return true
+}
+
+// Dump implements ast.Node
+func (n *mathBlock) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+// mathDelimiter opens and closes display math
+var mathDelimiter = []byte("$$")
+
+// mathBlockParser parses display math blocks:
+//
+//	$$
+//	\int_0^1 x^2 \, dx
+//	$$
+//
+// A block may also be written on a single line: $$ E = mc^2 $$
+//
+// An opening $$ with other text after it on its line, or without a closing $$ before
+// the next blank line or the end of its container, is left to the paragraph (and the
+// inline parser).
+type mathBlockParser struct{}
+
+// Trigger implements parser.BlockParser
+func (b *mathBlockParser) Trigger() []byte {
+	return []byte{'$'}
+}
+
+// Open implements parser.BlockParser
+func (b *mathBlockParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	line, segment := reader.PeekLine()
+	pos := pc.BlockOffset()
+	if pos < 0 || !bytes.HasPrefix(line[pos:], mathDelimiter) {
+		return nil, parser.NoChildren
+	}
+
+	start := segment.Start + pos + len(mathDelimiter)
+	rest := util.TrimRightSpace(line[pos+len(mathDelimiter):])
+
+	// $$ on a line of its own opens a block that ends at the next line ending in $$
+	if util.IsBlank(rest) {
+		source := reader.Source()
+		lineStart := bytes.LastIndexByte(source[:segment.Start], '\n') + 1
+		if !mathBlockCloses(source, segment.Stop, source[lineStart:segment.Start+pos]) {
+			return nil, parser.NoChildren
+		}
+		return &mathBlock{}, parser.NoChildren
+	}
+
+	// Single line block: $$ ... $$, with nothing after the closing delimiter
+	if !bytes.HasSuffix(rest, mathDelimiter) {
+		return nil, parser.NoChildren
+	}
+	tex := rest[:len(rest)-len(mathDelimiter)]
+	if util.IsBlank(tex) || bytes.Contains(tex, mathDelimiter) {
+		return nil, parser.NoChildren
+	}
+	node := &mathBlock{closed: true}
+	node.Lines().Append(text.NewSegment(start, start+len(tex)))
+	return node, parser.NoChildren
+}
+
+// mathBlockCloses reports whether a line of source after offset from ends in $$,
+// closing a block opened by a $$ line just before it. prefix is the opening line up to
+// the $$: the markers and indentation of the containers the block is in. The scan stops
+// at a blank line or a line outside those containers, which the block can't span.
+func mathBlockCloses(source []byte, from int, prefix []byte) bool {
+	for from < len(source) {
+		line := source[from:]
+		if end := bytes.IndexByte(line, '\n'); end != -1 {
+			line = line[:end+1]
+		}
+		from += len(line)
+
+		content, ok := containedLine(line, prefix)
+		if !ok || util.IsBlank(content) {
+			return false
+		}
+		if bytes.HasSuffix(util.TrimRightSpace(content), mathDelimiter) {
+			return true
+		}
+	}
+	return false
+}
+
+// containedLine returns line without the container prefix of an opening line, or false
+// if line isn't in the same containers: each > of prefix (a block quote) must be matched
+// by a > in line, and every other character (list markers and indentation) by a space
+func containedLine(line, prefix []byte) ([]byte, bool) {
+	i := 0
+	for j, c := range prefix {
+		switch {
+		case i < len(line) && c == '>' && line[i] == '>':
+			i++
+		case c == '>':
+			return nil, false
+		case i < len(line) && (line[i] == ' ' || line[i] == '\t'):
+			i++
+		case j > 0 && prefix[j-1] == '>':
+			// The space after a > is optional
+		default:
+			return nil, false
+		}
+	}
+	return line[i:], true
+}
+
+// Continue implements parser.BlockParser
+func (b *mathBlockParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	n := node.(*mathBlock)
+	if n.closed {
+		return parser.Close
+	}
+
+	line, segment := reader.PeekLine()
+	trimmed := util.TrimRightSpace(line)
+	if bytes.HasSuffix(trimmed, mathDelimiter) {
+		// Closing line, possibly with the end of the formula before the delimiter
+		content := trimmed[:len(trimmed)-len(mathDelimiter)]
+		if !util.IsBlank(content) {
+			n.Lines().Append(text.NewSegment(segment.Start, segment.Start+len(content)))
+		}
+		// Consume the delimiter so it doesn't open another block
+		reader.Advance(len(trimmed))
+		n.closed = true
+		return parser.Close
+	}
+
+	n.Lines().Append(segment)
+	return parser.Continue | parser.NoChildren
+}
+
+// Close implements parser.BlockParser
+func (b *mathBlockParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {}
+
+// CanInterruptParagraph implements parser.BlockParser
+func (b *mathBlockParser) CanInterruptParagraph() bool {
+	return true
+}
+
+// CanAcceptIndentedLine implements parser.BlockParser
+func (b *mathBlockParser) CanAcceptIndentedLine() bool {
+	return false
+}
+
+// mathInlineParser parses $...$ and $$...$$ math inside a paragraph.
+// Following pandoc, the opening $ must not be followed by a space and the closing $
+// must not be preceded by one or followed by a digit, so "$5 and $10" stays text.
+// A closing $ also can't be part of a $$ delimiter.
+type mathInlineParser struct{}
+
+// Trigger implements parser.InlineParser
+func (p *mathInlineParser) Trigger() []byte {
+	return []byte{'$'}
+}
+
+// Parse implements parser.InlineParser
+func (p *mathInlineParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, _ := block.PeekLine()
+
+	if bytes.HasPrefix(line, mathDelimiter) {
+		end := bytes.Index(line[len(mathDelimiter):], mathDelimiter)
+		if end <= 0 {
+			return nil
+		}
+		tex := line[len(mathDelimiter) : len(mathDelimiter)+end]
+		if util.IsBlank(tex) {
+			return nil
+		}
+		block.Advance(end + 2*len(mathDelimiter))
+		return &mathInline{tex: string(tex), display: true}
+	}
+
+	if len(line) < 3 || util.IsSpace(line[1]) {
+		return nil
+	}
+	for i := 2; i < len(line); i++ {
+		if line[i] != '$' || line[i-1] == '\\' || line[i-1] == '$' || util.IsSpace(line[i-1]) {
+			continue
+		}
+		if i+1 < len(line) && (line[i+1] == '$' || (line[i+1] >= '0' && line[i+1] <= '9')) {
+			continue
+		}
+		block.Advance(i + 1)
+		return &mathInline{tex: string(line[1:i])}
+	}
+	return nil
+}
+
+// mathRenderer renders math nodes as MathML, which browsers display natively,
+// so formulas need no JavaScript, fonts or network access.
+type mathRenderer struct {
+	doc *treeblood.Pitziil // Shared by all formulas of a document so \newcommand macros carry over
+}
+
+// newMathRenderer creates a mathRenderer for one document
+func newMathRenderer() *mathRenderer {
+	doc := treeblood.NewDocument(nil, false)
+	doc.PrintOneLine = true
+	return &mathRenderer{doc: doc}
+}
+
+// RegisterFuncs implements renderer.NodeRenderer
+func (r *mathRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(kindMathInline, r.renderMathInline)
+	reg.Register(kindMathBlock, r.renderMathBlock)
+}
+
+func (r *mathRenderer) renderMathInline(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+
+	n := node.(*mathInline)
+	if n.display {
+		r.writeMath(w, n.tex, true, "$$")
+	} else {
+		r.writeMath(w, n.tex, false, "$")
+	}
+	return ast.WalkSkipChildren, nil
+}
+
+func (r *mathRenderer) renderMathBlock(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+
+	tex := string(linesValue(node.Lines(), source))
+	_, _ = w.WriteString("<div class=\"math math-display\">")
+	r.writeMath(w, tex, true, "$$")
+	_, _ = w.WriteString("</div>\n")
+	return ast.WalkSkipChildren, nil
+}
+
+// writeMath writes tex as MathML. TeX that fails to convert is shown as source,
+// with its delimiters, and the error as a tooltip.
+func (r *mathRenderer) writeMath(w util.BufWriter, tex string, display bool, delimiter string) {
+	var mathML string
+	var err error
+	if display {
+		mathML, err = r.doc.DisplayStyle(tex)
+	} else {
+		mathML, err = r.doc.TextStyle(tex)
+	}
+
+	if err != nil || mathML == "" {
+		title := "invalid math"
+		if err != nil {
+			title = err.Error()
+		}
+		_, _ = w.WriteString("<code class=\"math-error\" title=\"" + htmlpkg.EscapeString(title) + "\">" +
+			htmlpkg.EscapeString(delimiter+tex+delimiter) + "</code>")
+		return
+	}
+	_, _ = w.WriteString(strings.TrimSpace(mathML))
+}
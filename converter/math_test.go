@@ -0,0 +1,177 @@
+package converter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMath_Inline(t *testing.T) {
+	c := newWithExtensions(t, "math")
+	result := bodyOf(convert(t, c, "The area is $\\pi r^2$ exactly.\n"))
+
+	if !strings.Contains(result, "The area is <math") {
+		t.Errorf("expected inline MathML, got:\n%s", result)
+	}
+	if !strings.Contains(result, `display="inline"`) {
+		t.Error("expected inline math to use display=\"inline\"")
+	}
+	if !strings.Contains(result, "<mi>π</mi>") {
+		t.Error("expected \\pi to be converted")
+	}
+	if strings.Contains(result, "$\\pi") || strings.Contains(result, "r^2$") {
+		t.Error("expected delimiters to be removed")
+	}
+}
+
+func TestMath_Block(t *testing.T) {
+	c := newWithExtensions(t, "math")
+	result := bodyOf(convert(t, c, "Before\n\n$$\n\\frac{a}{b}\n$$\n\nAfter\n"))
+
+	if !strings.Contains(result, `<div class="math math-display"><math`) {
+		t.Fatalf("expected display math block, got:\n%s", result)
+	}
+	if !strings.Contains(result, `display="block"`) {
+		t.Error("expected display math to use display=\"block\"")
+	}
+	if !strings.Contains(result, "<mfrac>") {
+		t.Error("expected \\frac to be converted")
+	}
+	if !strings.Contains(result, "<p>After</p>") {
+		t.Error("expected parsing to resume after the closing $$")
+	}
+}
+
+func TestMath_SingleLineBlock(t *testing.T) {
+	c := newWithExtensions(t, "math")
+	result := bodyOf(convert(t, c, "$$ E = mc^2 $$\nNext paragraph\n"))
+
+	if !strings.Contains(result, `<div class="math math-display">`) {
+		t.Errorf("expected single line display math, got:\n%s", result)
+	}
+	if !strings.Contains(result, "<p>Next paragraph</p>") {
+		t.Error("expected the following line to be a paragraph")
+	}
+}
+
+func TestMath_UnclosedBlock(t *testing.T) {
+	c := newWithExtensions(t, "math")
+	result := bodyOf(convert(t, c, "Cost\n\n$$\n\n# Heading\n\nText with *emphasis*\n"))
+
+	if strings.Contains(result, `<div class="math math-display">`) {
+		t.Errorf("expected no display math without a closing $$, got:\n%s", result)
+	}
+	if !strings.Contains(result, "<p>$$</p>") {
+		t.Error("expected the lone $$ to stay paragraph text")
+	}
+	if !strings.Contains(result, `<h1 id="heading">Heading</h1>`) || !strings.Contains(result, "<em>emphasis</em>") {
+		t.Error("expected the rest of the document to be parsed as markdown")
+	}
+}
+
+func TestMath_BlockCloseNotSearchedPastBlankLine(t *testing.T) {
+	c := newWithExtensions(t, "math")
+	result := bodyOf(convert(t, c, "$$\n\n```\necho $$\n```\n"))
+
+	if strings.Contains(result, "<math") {
+		t.Errorf("expected the $$ in the code block not to close math, got:\n%s", result)
+	}
+	if !strings.Contains(result, "<p>$$</p>") || !strings.Contains(result, "<pre><code>echo $$\n</code></pre>") {
+		t.Errorf("expected the lone $$ as text and the fenced block as code, got:\n%s", result)
+	}
+}
+
+func TestMath_BlockCloseNotSearchedPastContainer(t *testing.T) {
+	c := newWithExtensions(t, "math")
+	result := bodyOf(convert(t, c, "> $$\n> x^2\nCosts $$\n"))
+	if strings.Contains(result, "<math") {
+		t.Errorf("expected a $$ outside the quote not to close math in it, got:\n%s", result)
+	}
+
+	result = bodyOf(convert(t, c, "> $$\n> x^2\n> $$\n\n- $$\n  y^2\n  $$\n"))
+	if strings.Count(result, `<div class="math math-display">`) != 2 {
+		t.Errorf("expected display math in the quote and the list item, got:\n%s", result)
+	}
+}
+
+func TestMath_TextAfterSingleLineBlock(t *testing.T) {
+	c := newWithExtensions(t, "math")
+	result := bodyOf(convert(t, c, "$$ x $$ trailing\n\nNext\n"))
+
+	if strings.Contains(result, `<div class="math math-display">`) {
+		t.Errorf("expected no display math block, got:\n%s", result)
+	}
+	if !strings.Contains(result, "<p><math") || !strings.Contains(result, " trailing</p>") {
+		t.Error("expected inline display math followed by the text in a paragraph")
+	}
+	if !strings.Contains(result, "<p>Next</p>") {
+		t.Error("expected the following paragraph to be parsed")
+	}
+}
+
+func TestMath_BlockInterruptsParagraph(t *testing.T) {
+	c := newWithExtensions(t, "math")
+	result := bodyOf(convert(t, c, "Consider\n$$\nx + y\n$$\n"))
+
+	if !strings.Contains(result, "<p>Consider</p>") {
+		t.Errorf("expected paragraph to end before the math block, got:\n%s", result)
+	}
+	if !strings.Contains(result, `<div class="math math-display">`) {
+		t.Error("expected display math block")
+	}
+}
+
+func TestMath_NotMath(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		want     string
+	}{
+		{"currency", "It costs $5 and $10.\n", "It costs $5 and $10."},
+		{"space after opening", "Between $ x $ here.\n", "Between $ x $ here."},
+		{"escaped dollar", "Price \\$5 and $6\n", "Price $5 and $6"},
+		{"code span", "Use `$x$` literally.\n", "<code>$x$</code>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newWithExtensions(t, "math")
+			result := bodyOf(convert(t, c, tt.markdown))
+			if strings.Contains(result, "<math") {
+				t.Errorf("expected no math in %q", tt.markdown)
+			}
+			if !strings.Contains(result, tt.want) {
+				t.Errorf("expected %q in output, got:\n%s", tt.want, result)
+			}
+		})
+	}
+}
+
+func TestMath_NotParsedAsMarkdown(t *testing.T) {
+	c := newWithExtensions(t, "math")
+	result := bodyOf(convert(t, c, "$a_1 * b_2 * c$\n"))
+
+	if strings.Contains(result, "<em>") {
+		t.Error("expected math content not to be parsed as emphasis")
+	}
+}
+
+func TestMath_InvalidShownAsSource(t *testing.T) {
+	c := newWithExtensions(t, "math")
+	result := bodyOf(convert(t, c, "$$\n\\frac{1\n$$\n"))
+
+	if !strings.Contains(result, `<code class="math-error"`) {
+		t.Errorf("expected invalid math to be shown as an error, got:\n%s", result)
+	}
+	if !strings.Contains(result, "\\frac{1") {
+		t.Error("expected the TeX source to be shown")
+	}
+}
+
+func TestMath_MacrosShared(t *testing.T) {
+	c := newWithExtensions(t, "math")
+	result := bodyOf(convert(t, c, "$\\newcommand{\\R}{\\mathbb{R}}$ and $x \\in \\R$\n"))
+
+	if !strings.Contains(result, "ℝ") {
+		t.Errorf("expected macro defined in one formula to be usable in the next, got:\n%s", result)
+	}
+}
@@ -0,0 +1,222 @@
+package converter
+
+import (
+	"bytes"
+	htmlpkg "html"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// DefaultTOCDepth is the deepest heading level included in a table of contents by default
+const DefaultTOCDepth = 3
+
+// Heading is a document heading with the ID assigned by parser.WithAutoHeadingID
+type Heading struct {
+	Level int
+	ID    string
+	Text  string
+}
+
+// kindTOC is the AST node kind for a table of contents placeholder
+var kindTOC = ast.NewNodeKind("TOC")
+
+// tocNode replaces a [TOC] or <!-- toc --> marker in the document
+type tocNode struct {
+	ast.BaseBlock
+	headings []Heading
+}
+
+// Kind implements ast.Node
+func (n *tocNode) Kind() ast.NodeKind {
+	return kindTOC
+}
+
+// Dump implements ast.Node
+func (n *tocNode) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+// tocRenderer renders tocNode placeholders as a nested navigation list
+type tocRenderer struct{}
+
+// RegisterFuncs implements renderer.NodeRenderer
+func (r *tocRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(kindTOC, r.renderTOC)
+}
+
+func (r *tocRenderer) renderTOC(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		_, _ = w.WriteString(renderTOCNav(node.(*tocNode).headings, "toc"))
+	}
+	return ast.WalkSkipChildren, nil
+}
+
+// collectHeadings returns the headings of a parsed document in order
+func collectHeadings(doc ast.Node, source []byte) []Heading {
+	var headings []Heading
+	_ = ast.Walk(doc, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		heading, ok := node.(*ast.Heading)
+		if !ok {
+			return ast.WalkContinue, nil
+		}
+
+		var id string
+		if value, found := heading.AttributeString("id"); found {
+			switch v := value.(type) {
+			case []byte:
+				id = string(v)
+			case string:
+				id = v
+			}
+		}
+
+		headings = append(headings, Heading{
+			Level: heading.Level,
+			ID:    id,
+			Text:  strings.TrimSpace(plainText(heading, source)),
+		})
+		return ast.WalkSkipChildren, nil
+	})
+	return headings
+}
+
+// plainText concatenates the text content of a node's descendants
+func plainText(node ast.Node, source []byte) string {
+	var buf bytes.Buffer
+	for child := node.FirstChild(); child != nil; child = child.NextSibling() {
+		switch n := child.(type) {
+		case *ast.Text:
+			buf.Write(n.Segment.Value(source))
+			if n.SoftLineBreak() || n.HardLineBreak() {
+				buf.WriteByte(' ')
+			}
+		case *ast.String:
+			buf.Write(n.Value)
+		default:
+			buf.WriteString(plainText(child, source))
+		}
+	}
+	return buf.String()
+}
+
+// replaceTOCMarkers swaps every [TOC] paragraph or <!-- toc --> comment for a
+// table of contents built from headings. Returns true if any marker was found.
+func replaceTOCMarkers(doc ast.Node, source []byte, headings []Heading) bool {
+	var markers []ast.Node
+	for child := doc.FirstChild(); child != nil; child = child.NextSibling() {
+		if isTOCMarker(child, source) {
+			markers = append(markers, child)
+		}
+	}
+
+	for _, marker := range markers {
+		doc.ReplaceChild(doc, marker, &tocNode{headings: headings})
+	}
+	return len(markers) > 0
+}
+
+// isTOCMarker reports whether a top-level block is a table of contents marker
+func isTOCMarker(node ast.Node, source []byte) bool {
+	switch n := node.(type) {
+	case *ast.Paragraph:
+		raw := linesValue(n.Lines(), source)
+		return strings.EqualFold(string(bytes.TrimSpace(raw)), "[TOC]")
+	case *ast.HTMLBlock:
+		comment := string(bytes.TrimSpace(linesValue(n.Lines(), source)))
+		if !strings.HasPrefix(comment, "<!--") || !strings.HasSuffix(comment, "-->") {
+			return false
+		}
+		comment = strings.TrimSpace(comment[len("<!--") : len(comment)-len("-->")])
+		return strings.EqualFold(comment, "toc")
+	}
+	return false
+}
+
+// linesValue concatenates the source of a block's lines
+func linesValue(lines *text.Segments, source []byte) []byte {
+	var buf bytes.Buffer
+	for i := 0; i < lines.Len(); i++ {
+		segment := lines.At(i)
+		buf.Write(segment.Value(source))
+	}
+	return buf.Bytes()
+}
+
+// filterHeadings returns the headings at or above the given depth that have an ID
+func filterHeadings(headings []Heading, depth int) []Heading {
+	if depth <= 0 {
+		depth = DefaultTOCDepth
+	}
+	var filtered []Heading
+	for _, h := range headings {
+		if h.Level <= depth && h.ID != "" {
+			filtered = append(filtered, h)
+		}
+	}
+	return filtered
+}
+
+// renderTOCNav renders headings as a nested list inside a <nav> with the given class.
+// Levels are relative, so a document starting at ## still gets a flat top level.
+func renderTOCNav(headings []Heading, class string) string {
+	if len(headings) == 0 {
+		return ""
+	}
+
+	minLevel := headings[0].Level
+	for _, h := range headings {
+		if h.Level < minLevel {
+			minLevel = h.Level
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("<nav class=\"" + class + "\">\n<ul>\n")
+
+	depth := 0 // Current nesting depth below the top-level list
+	for i, h := range headings {
+		level := h.Level - minLevel
+
+		if i > 0 {
+			if level > depth {
+				// Open nested lists (skipped levels get empty items so nesting stays valid)
+				for depth < level {
+					sb.WriteString("\n<ul>\n")
+					depth++
+					if depth < level {
+						sb.WriteString("<li>")
+					}
+				}
+			} else {
+				sb.WriteString("</li>\n")
+				for depth > level {
+					sb.WriteString("</ul>\n</li>\n")
+					depth--
+				}
+			}
+		} else {
+			for depth < level {
+				sb.WriteString("<li>\n<ul>\n")
+				depth++
+			}
+		}
+
+		sb.WriteString("<li><a href=\"#" + htmlpkg.EscapeString(h.ID) + "\">" + htmlpkg.EscapeString(h.Text) + "</a>")
+	}
+
+	sb.WriteString("</li>\n")
+	for depth > 0 {
+		sb.WriteString("</ul>\n</li>\n")
+		depth--
+	}
+	sb.WriteString("</ul>\n</nav>\n")
+
+	return sb.String()
+}
@@ -0,0 +1,196 @@
+package converter
+
+import (
+	"strings"
+	"testing"
+)
+
+const tocDocument = `# Design
+
+[TOC]
+
+## Goals
+
+### Non-goals
+
+#### Deep detail
+
+## Architecture *overview*
+
+### Components
+`
+
+// bodyOf returns the part of a page after </head>, so assertions don't match template CSS
+func bodyOf(result string) string {
+	if idx := strings.Index(result, "</head>"); idx != -1 {
+		return result[idx:]
+	}
+	return result
+}
+
+func TestTOC_MarkerReplaced(t *testing.T) {
+	c := New()
+	result := bodyOf(convert(t, c, tocDocument))
+
+	if strings.Contains(result, "[TOC]") {
+		t.Error("expected [TOC] marker to be replaced")
+	}
+	if !strings.Contains(result, `<nav class="toc">`) {
+		t.Fatalf("expected TOC nav in output, got:\n%s", result)
+	}
+
+	wants := []string{
+		`<a href="#design">Design</a>`,
+		`<a href="#goals">Goals</a>`,
+		`<a href="#non-goals">Non-goals</a>`,
+		`<a href="#architecture-overview">Architecture overview</a>`,
+		`<a href="#components">Components</a>`,
+	}
+	for _, want := range wants {
+		if !strings.Contains(result, want) {
+			t.Errorf("expected %q in TOC", want)
+		}
+	}
+
+	// Default depth is 3, so h4 is left out
+	if strings.Contains(result, `href="#deep-detail"`) {
+		t.Error("expected h4 to be excluded at default depth")
+	}
+}
+
+func TestTOC_HTMLCommentMarker(t *testing.T) {
+	c := New()
+	result := bodyOf(convert(t, c, "# Title\n\n<!-- toc -->\n\n## Section\n"))
+
+	if !strings.Contains(result, `<nav class="toc">`) {
+		t.Error("expected <!-- toc --> to be replaced with a TOC")
+	}
+	if strings.Contains(result, "<!-- toc -->") {
+		t.Error("expected marker comment to be removed")
+	}
+}
+
+func TestTOC_MarkerMustBeOnItsOwn(t *testing.T) {
+	c := New()
+	result := convert(t, c, "# Title\n\nSee the [TOC] below.\n")
+
+	if strings.Contains(result, `<nav class="toc">`) {
+		t.Error("expected inline [TOC] text not to become a TOC")
+	}
+}
+
+func TestTOC_Depth(t *testing.T) {
+	c := New()
+	c.SetTOCDepth(2)
+	result := convert(t, c, tocDocument)
+
+	if !strings.Contains(result, `href="#goals"`) {
+		t.Error("expected h2 in TOC at depth 2")
+	}
+	if strings.Contains(result, `href="#non-goals"`) {
+		t.Error("expected h3 to be excluded at depth 2")
+	}
+
+	c.SetTOCDepth(6)
+	result = convert(t, c, tocDocument)
+	if !strings.Contains(result, `href="#deep-detail"`) {
+		t.Error("expected h4 in TOC at depth 6")
+	}
+}
+
+func TestTOC_NoMarkerNoTOC(t *testing.T) {
+	c := New()
+	result := bodyOf(convert(t, c, "# Title\n\n## Section\n"))
+
+	if strings.Contains(result, `class="toc`) {
+		t.Error("expected no TOC without a marker or sidebar")
+	}
+}
+
+func TestTOC_Sidebar(t *testing.T) {
+	c := New()
+	c.SetTOCSidebar(true)
+	result := convert(t, c, "# Title\n\n## Section\n")
+
+	if !strings.Contains(result, `<body class="has-toc">`) {
+		t.Error("expected body to be marked as having a TOC")
+	}
+
+	sidebarIdx := strings.Index(result, `<nav class="toc toc-sidebar">`)
+	articleIdx := strings.Index(result, `<article class="markdown-body">`)
+	if sidebarIdx == -1 {
+		t.Fatal("expected sidebar TOC in output")
+	}
+	if sidebarIdx > articleIdx {
+		t.Error("expected sidebar to come before the article")
+	}
+	if !strings.Contains(result[sidebarIdx:articleIdx], `href="#section"`) {
+		t.Error("expected sidebar to link to headings")
+	}
+}
+
+func TestTOC_SidebarOmittedWithoutHeadings(t *testing.T) {
+	c := New()
+	c.SetTOCSidebar(true)
+	result := bodyOf(convert(t, c, "Just a paragraph.\n"))
+
+	if strings.Contains(result, "toc-sidebar") || strings.Contains(result, "has-toc") {
+		t.Error("expected no sidebar for a document without headings")
+	}
+}
+
+func TestTOC_HeadingsAccessor(t *testing.T) {
+	c := New()
+	convert(t, c, "# One\n\n## Two `code`\n")
+
+	headings := c.Headings()
+	if len(headings) != 2 {
+		t.Fatalf("expected 2 headings, got %d", len(headings))
+	}
+	if headings[1].Level != 2 || headings[1].ID != "two-code" || headings[1].Text != "Two code" {
+		t.Errorf("unexpected heading: %+v", headings[1])
+	}
+}
+
+func TestRenderTOCNav_Nesting(t *testing.T) {
+	tests := []struct {
+		name     string
+		headings []Heading
+		want     string
+	}{
+		{
+			name:     "flat",
+			headings: []Heading{{2, "a", "A"}, {2, "b", "B"}},
+			want:     `<nav class="toc"><ul><li><a href="#a">A</a></li><li><a href="#b">B</a></li></ul></nav>`,
+		},
+		{
+			name:     "nested",
+			headings: []Heading{{1, "a", "A"}, {2, "b", "B"}, {1, "c", "C"}},
+			want:     `<nav class="toc"><ul><li><a href="#a">A</a><ul><li><a href="#b">B</a></li></ul></li><li><a href="#c">C</a></li></ul></nav>`,
+		},
+		{
+			name:     "skipped level",
+			headings: []Heading{{1, "a", "A"}, {3, "b", "B"}},
+			want:     `<nav class="toc"><ul><li><a href="#a">A</a><ul><li><ul><li><a href="#b">B</a></li></ul></li></ul></li></ul></nav>`,
+		},
+		{
+			name:     "starts deeper than minimum",
+			headings: []Heading{{3, "a", "A"}, {2, "b", "B"}},
+			want:     `<nav class="toc"><ul><li><ul><li><a href="#a">A</a></li></ul></li><li><a href="#b">B</a></li></ul></nav>`,
+		},
+		{
+			name:     "escapes text",
+			headings: []Heading{{1, "a", "<b> & c"}},
+			want:     `<nav class="toc"><ul><li><a href="#a">&lt;b&gt; &amp; c</a></li></ul></nav>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := strings.ReplaceAll(renderTOCNav(tt.headings, "toc"), "\n", "")
+			if got != tt.want {
+				t.Errorf("renderTOCNav() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
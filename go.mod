@@ -3,6 +3,12 @@ module mdview
 go 1.24.0
 
 require (
+	github.com/BurntSushi/toml v1.4.0
+	github.com/alecthomas/chroma/v2 v2.14.0
+	github.com/wyatt915/treeblood v0.1.16
 	github.com/yuin/goldmark v1.7.8
 	golang.org/x/sys v0.39.0
+	gopkg.in/yaml.v3 v3.0.1
 )
+
+require github.com/dlclark/regexp2 v1.11.0 // indirect
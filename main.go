@@ -1,49 +1,85 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"flag"
 	"fmt"
+	"io"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
 	"mdview/archive"
+	"mdview/batch"
 	"mdview/browser"
+	"mdview/config"
 	"mdview/converter"
 	"mdview/output"
 	"mdview/register"
+	"mdview/server"
 	"mdview/templates"
+	"mdview/watch"
 )
 
 const version = "1.1.3"
 
+// stdioPath as the input or output path reads markdown from stdin or writes HTML to stdout
+const stdioPath = "-"
+
+// extensionsUsage is the help text of the --extensions flag
+var extensionsUsage = "Comma-separated markdown extensions to enable, or disable with a - prefix, e.g. deflist,-hardwraps (available: " +
+	strings.Join(converter.ExtensionNames(), ", ") + ")"
+
 func main() {
+	// Subcommands have their own flag sets
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		runServeCommand(os.Args[2:])
+		return
+	}
+
 	// Define flags
 	templateName := flag.String("template", "default", "Template name to use for styling")
+	templateDir := flag.String("template-dir", "", "Directory of user templates, searched before ~/.config/mdview/templates and the built-in ones")
 	showVersion := flag.Bool("version", false, "Show version information")
 	listTemplates := flag.Bool("list-templates", false, "List available templates")
 	noBrowser := flag.Bool("no-browser", false, "Don't open browser after conversion")
+	browserExe := flag.String("browser", "", "Browser executable to open the output with (default: the system default browser)")
 	selfContained := flag.Bool("self-contained", false, "Embed images and linked local .md files as base64 data URIs instead of file:// URLs")
 	preload := flag.Bool("preload", false, "Preload all images in a directory when first image is referenced (use with --self-contained)")
 	maxPages := flag.Int("max-pages", 10, "Maximum number of pages to embed in archive (use with --self-contained)")
+	navLayout := flag.String("nav", string(archive.NavByDirectory), "Archive sidebar listing the pages: dirs (by directory), links (by where each page is linked from) or none (use with --self-contained)")
 	doRegister := flag.Bool("register", false, "Register mdview as the default program for .md files")
 	doUnregister := flag.Bool("unregister", false, "Unregister mdview as the default program for .md files")
+	doClean := flag.Bool("clean", false, "Remove all output mdview generated in its temp directory, then exit")
+	watchMode := flag.Bool("watch", false, "Keep running and regenerate the output (reloading the browser tab) when the input or its images change")
+	toc := flag.Bool("toc", false, "Add a table of contents sidebar (a [TOC] or <!-- toc --> line in the document always becomes one in place)")
+	tocDepth := flag.Int("toc-depth", converter.DefaultTOCDepth, "Deepest heading level (1-6) included in tables of contents")
+	highlight := flag.Bool("highlight", false, "Highlight code blocks at conversion time and leave highlight.js out of the output")
+	extensionSpec := flag.String("extensions", "", extensionsUsage)
+	jobs := flag.Int("jobs", 0, "Number of files converted in parallel when the input is a directory or pattern (default: one per CPU)")
 
 	// Custom usage message
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "mdview - Markdown to HTML viewer\n\n")
-		fmt.Fprintf(os.Stderr, "Usage: mdview [options] <input.md> [output.html]\n\n")
+		fmt.Fprintf(os.Stderr, "Usage: mdview [options] <input.md> [output.html]\n")
+		fmt.Fprintf(os.Stderr, "       mdview [options] - - < input.md > output.html\n")
+		fmt.Fprintf(os.Stderr, "       mdview [options] <directory|pattern> [output-dir]\n")
+		fmt.Fprintf(os.Stderr, "       mdview serve [options] [directory]\n\n")
 		fmt.Fprintf(os.Stderr, "Arguments:\n")
-		fmt.Fprintf(os.Stderr, "  input.md      Path to the markdown file to convert\n")
-		fmt.Fprintf(os.Stderr, "  output.html   Optional output path (default: temp file in %%LocalAppData%%\\mdview)\n\n")
+		fmt.Fprintf(os.Stderr, "  input.md      Path to the markdown file to convert, or - for stdin\n")
+		fmt.Fprintf(os.Stderr, "  output.html   Optional output path, or - for stdout (default: temp file in %%LocalAppData%%\\mdview)\n")
+		fmt.Fprintf(os.Stderr, "  directory     Convert every .md file beneath it into a mirrored tree of .html pages in output-dir\n")
+		fmt.Fprintf(os.Stderr, "  pattern       Glob such as \"docs/*.md\" selecting the .md files (and directories) to convert\n\n")
 		fmt.Fprintf(os.Stderr, "Options:\n")
-		flag.VisitAll(func(f *flag.Flag) {
-			fmt.Fprintf(os.Stderr, "  --%s", f.Name)
-			if f.DefValue != "false" && f.DefValue != "" {
-				fmt.Fprintf(os.Stderr, " %s", f.DefValue)
-			}
-			fmt.Fprintf(os.Stderr, "\n        %s\n", f.Usage)
-		})
+		printFlags(flag.CommandLine)
+		fmt.Fprintf(os.Stderr, "\nOption defaults can be set in a %s file next to the input or in a parent directory,\n", config.FileName)
+		fmt.Fprintf(os.Stderr, "and in %s for every input. Options given on the command line take precedence.\n", config.UserPath())
 	}
 
 	flag.Parse()
@@ -74,6 +110,22 @@ func main() {
 		os.Exit(0)
 	}
 
+	// Handle clean flag
+	if *doClean {
+		removed, err := output.Purge()
+		reportRemoved(removed)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error cleaning: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	// Settings from .mdview.yaml config files apply to flags not given on the command line
+	cfg := loadConfig(flag.CommandLine, configStartDir(flag.Arg(0)))
+
+	useTemplateDir(*templateDir)
+
 	// Handle list-templates flag
 	if *listTemplates {
 		names, err := templates.List()
@@ -100,10 +152,12 @@ func main() {
 	var outputPath string
 	if len(args) >= 2 {
 		outputPath = args[1]
+	} else if cfg.Output != "" {
+		outputPath = configOutputPath(inputPath, cfg.Output)
 	}
 
-	// Validate input file exists
-	if _, err := os.Stat(inputPath); os.IsNotExist(err) {
+	// Validate input file exists (patterns are expanded by the batch conversion)
+	if _, err := os.Stat(inputPath); inputPath != stdioPath && !batch.IsPattern(inputPath) && os.IsNotExist(err) {
 		fmt.Fprintf(os.Stderr, "Error: input file does not exist: %s\n", inputPath)
 		os.Exit(1)
 	}
@@ -115,119 +169,657 @@ func main() {
 		os.Exit(1)
 	}
 
+	extensions, err := parseExtensions(cfg, *extensionSpec)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	nav, err := archive.ParseNavLayout(*navLayout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	opts := options{
+		templateName:  *templateName,
+		openBrowser:   !*noBrowser,
+		browser:       *browserExe,
+		selfContained: *selfContained,
+		preload:       *preload,
+		maxPages:      *maxPages,
+		nav:           nav,
+		watch:         *watchMode,
+		toc:           *toc,
+		tocDepth:      *tocDepth,
+		highlight:     *highlight,
+		extensions:    extensions,
+		jobs:          *jobs,
+	}
+
 	// Run the conversion
-	if err := run(inputPath, outputPath, *templateName, !*noBrowser, *selfContained, *preload, *maxPages); err != nil {
+	if err := run(inputPath, outputPath, opts); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// loadConfig loads the config files for startDir and applies them to the flags of fs
+// that weren't given on the command line
+func loadConfig(fs *flag.FlagSet, startDir string) *config.Config {
+	cfg, err := config.Load(startDir)
+	if err == nil {
+		err = cfg.Apply(fs)
+		if err != nil && len(cfg.Files) > 0 {
+			err = fmt.Errorf("%w (config files: %s)", err, strings.Join(cfg.Files, ", "))
+		}
+	}
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
+	return cfg
+}
+
+// configStartDir returns the directory the search for a project config starts from:
+// the input directory, the directory of the input file or pattern, or the working
+// directory for stdin
+func configStartDir(input string) string {
+	if input == "" || input == stdioPath {
+		return "."
+	}
+	for batch.IsPattern(input) {
+		input = filepath.Dir(input)
+	}
+	if info, err := os.Stat(input); err == nil && info.IsDir() {
+		return input
+	}
+	return filepath.Dir(input)
+}
+
+// configOutputPath returns where the output for input goes when the config sets an
+// output directory: the directory itself for a batch conversion, otherwise an .html
+// file named after the input. Markdown from stdin still goes to a temp file.
+func configOutputPath(input, outputDir string) string {
+	if input == stdioPath {
+		return ""
+	}
+	if batch.IsBatchInput(input) {
+		return outputDir
+	}
+	base := filepath.Base(input)
+	return filepath.Join(outputDir, strings.TrimSuffix(base, filepath.Ext(base))+".html")
+}
+
+// parseExtensions applies the config's extensions, then the --extensions flag, to the defaults
+func parseExtensions(cfg *config.Config, spec string) (converter.Extensions, error) {
+	base, err := converter.ParseExtensions(string(cfg.Extensions), converter.DefaultExtensions())
+	if err != nil {
+		return base, fmt.Errorf("invalid extensions in config: %w", err)
+	}
+	return converter.ParseExtensions(spec, base)
+}
+
+// reportRemoved prints the files removed by --clean and their total size
+func reportRemoved(removed []output.RemovedFile) {
+	var total int64
+	for _, file := range removed {
+		fmt.Printf("Removed: %s\n", file.Path)
+		total += file.Size
+	}
+	fmt.Printf("Removed %d outputs (%s)\n", len(removed), formatSize(total))
+}
+
+// formatSize formats a byte count for display, e.g. 1.5 MB
+func formatSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	value, suffix := float64(bytes)/unit, "KB"
+	for _, next := range []string{"MB", "GB", "TB"} {
+		if value < unit {
+			break
+		}
+		value, suffix = value/unit, next
+	}
+	return fmt.Sprintf("%.1f %s", value, suffix)
+}
+
+// useTemplateDir adds a --template-dir directory in front of the default user template directories
+func useTemplateDir(dir string) {
+	if dir == "" {
+		return
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		fmt.Fprintf(os.Stderr, "Error: template directory does not exist: %s\n", dir)
+		os.Exit(1)
+	}
+	templates.SetDirs(append([]string{dir}, templates.DefaultDirs()...)...)
 }
 
-func run(inputPath, outputPath, templateName string, openBrowser, selfContained, preload bool, maxPages int) error {
-	// Determine output path
-	finalOutputPath, err := output.GetOutputPath(outputPath)
+// printFlags writes the usage lines for every flag in the set
+func printFlags(fs *flag.FlagSet) {
+	fs.VisitAll(func(f *flag.Flag) {
+		fmt.Fprintf(os.Stderr, "  --%s", f.Name)
+		if f.DefValue != "false" && f.DefValue != "" {
+			fmt.Fprintf(os.Stderr, " %s", f.DefValue)
+		}
+		fmt.Fprintf(os.Stderr, "\n        %s\n", f.Usage)
+	})
+}
+
+// runServeCommand handles `mdview serve`: a local preview server that renders
+// markdown files under a directory on request
+func runServeCommand(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	templateName := fs.String("template", "default", "Template name to use for styling")
+	templateDir := fs.String("template-dir", "", "Directory of user templates, searched before ~/.config/mdview/templates and the built-in ones")
+	port := fs.Int("port", 8080, "Port to listen on")
+	host := fs.String("host", "localhost", "Interface to listen on (use 0.0.0.0 to share on the network)")
+	noBrowser := fs.Bool("no-browser", false, "Don't open browser after starting the server")
+	browserExe := fs.String("browser", "", "Browser executable to open the server with (default: the system default browser)")
+	toc := fs.Bool("toc", false, "Add a table of contents sidebar to every page")
+	tocDepth := fs.Int("toc-depth", converter.DefaultTOCDepth, "Deepest heading level (1-6) included in tables of contents")
+	highlight := fs.Bool("highlight", false, "Highlight code blocks on the server instead of with highlight.js")
+	extensionSpec := fs.String("extensions", "", extensionsUsage)
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "mdview serve - Local markdown preview server\n\n")
+		fmt.Fprintf(os.Stderr, "Usage: mdview serve [options] [directory]\n\n")
+		fmt.Fprintf(os.Stderr, "Arguments:\n")
+		fmt.Fprintf(os.Stderr, "  directory     Directory to serve (default: current directory)\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		printFlags(fs)
+	}
+
+	fs.Parse(args)
+
+	rootDir := "."
+	if fs.NArg() >= 1 {
+		rootDir = fs.Arg(0)
+	}
+
+	cfg := loadConfig(fs, configStartDir(rootDir))
+	useTemplateDir(*templateDir)
+
+	// Validate template exists
+	if _, err := templates.Get(*templateName); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Use --list-templates to see available templates\n")
+		os.Exit(1)
+	}
+
+	extensions, err := parseExtensions(cfg, *extensionSpec)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	srv, err := server.New(rootDir, *templateName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	srv.SetTOCSidebar(*toc)
+	srv.SetTOCDepth(*tocDepth)
+	srv.SetHighlight(*highlight)
+	srv.SetExtensions(extensions)
+
+	// Listen before opening the browser so the first request can't race the server
+	listener, err := net.Listen("tcp", net.JoinHostPort(*host, fmt.Sprint(*port)))
 	if err != nil {
-		return fmt.Errorf("failed to determine output path: %w", err)
+		fmt.Fprintf(os.Stderr, "Error: failed to listen: %v\n", err)
+		os.Exit(1)
+	}
+
+	url := fmt.Sprintf("http://%s/", net.JoinHostPort(*host, fmt.Sprint(*port)))
+	fmt.Printf("Serving %s at %s (press Ctrl+C to stop)\n", srv.RootDir(), url)
+
+	if !*noBrowser {
+		if err := browser.OpenURLWith(*browserExe, url); err != nil {
+			// Don't fail on browser error, just warn
+			fmt.Fprintf(os.Stderr, "Warning: failed to open browser: %v\n", err)
+		}
+	}
+
+	if err := http.Serve(listener, srv); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// options holds the settings that control a conversion run
+type options struct {
+	templateName  string
+	openBrowser   bool
+	browser       string // Browser executable, empty for the system default
+	selfContained bool
+	preload       bool
+	maxPages      int
+	nav           archive.NavLayout
+	watch         bool
+	toc           bool
+	tocDepth      int
+	highlight     bool
+	extensions    converter.Extensions
+	reloadToken   string // Live reload build token (watch mode only)
+	jobs          int    // Files converted in parallel by a batch conversion (0 = one per CPU)
+}
+
+// statusOutput returns where progress messages go: stderr when the HTML goes to stdout
+func statusOutput(finalOutputPath string) io.Writer {
+	if finalOutputPath == stdioPath {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+func run(inputPath, outputPath string, opts options) error {
+	// Output without a path goes to the temp directory, so keep it from growing forever
+	if outputPath == "" {
+		if _, err := output.CleanupOldFiles(output.DefaultMaxAge, output.DefaultMaxSize); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to clean up old output: %v\n", err)
+		}
+	}
+
+	if inputPath != stdioPath && batch.IsBatchInput(inputPath) {
+		return runBatch(inputPath, outputPath, opts)
+	}
+
+	if opts.watch && (inputPath == stdioPath || outputPath == stdioPath) {
+		return fmt.Errorf("--watch needs an input file and an output file, not %s", stdioPath)
 	}
 
 	// Make input path absolute for better error messages
-	absInputPath, err := filepath.Abs(inputPath)
+	absInputPath := stdioPath
+	if inputPath != stdioPath {
+		var err error
+		absInputPath, err = filepath.Abs(inputPath)
+		if err != nil {
+			return fmt.Errorf("failed to resolve input path: %w", err)
+		}
+	}
+
+	// Determine output path; a file converted to the temp directory always goes to the same file
+	finalOutputPath := stdioPath
+	if outputPath != stdioPath {
+		outputKey := absInputPath
+		if absInputPath == stdioPath {
+			outputKey = ""
+		}
+		var err error
+		finalOutputPath, err = output.GetOutputPathFor(outputPath, outputKey)
+		if err != nil {
+			return fmt.Errorf("failed to determine output path: %w", err)
+		}
+	}
+
+	// Output in the temp directory is reused as long as nothing it was built from changes
+	reusable := outputPath == "" && absInputPath != stdioPath
+
+	if opts.watch {
+		// Watch output carries live reload, so it must not be reused by a later run
+		if reusable {
+			if err := output.RemoveBuild(finalOutputPath); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+			// Keep the cleanup of other runs away from the page while it is watched
+			stop, err := output.StartSession(finalOutputPath)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			} else {
+				defer stop()
+			}
+		}
+		return runWatch(absInputPath, finalOutputPath, opts)
+	}
+
+	settings := buildSettings(opts)
+	if reusable && output.UpToDate(finalOutputPath, settings) {
+		fmt.Printf("Up to date: %s\n", finalOutputPath)
+		openOutput(finalOutputPath, opts)
+		return nil
+	}
+
+	sources, err := convertFile(absInputPath, finalOutputPath, opts)
+	if err != nil {
+		return err
+	}
+	if reusable {
+		if err := output.WriteBuild(finalOutputPath, settings, sources); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+
+	openOutput(finalOutputPath, opts)
+	return nil
+}
+
+// buildSettings returns a fingerprint of everything besides the source files that
+// affects the output: the mdview binary, the conversion options and the template.
+// Returns "" if the template can't be read.
+func buildSettings(opts options) string {
+	tmpl, err := templates.Get(opts.templateName)
+	if err != nil {
+		return ""
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "mdview %s\n", version)
+	if exe, err := os.Executable(); err == nil {
+		if info, err := os.Stat(exe); err == nil {
+			fmt.Fprintf(h, "%s %d %d\n", exe, info.Size(), info.ModTime().UnixNano())
+		}
+	}
+	fmt.Fprintf(h, "%s %t %t %d %s %t %d %t %+v\n", opts.templateName, opts.selfContained, opts.preload,
+		opts.maxPages, opts.nav, opts.toc, opts.tocDepth, opts.highlight, opts.extensions)
+	for _, part := range []string{tmpl.HTML, tmpl.CSS, tmpl.JS, tmpl.Highlight, tmpl.Layout} {
+		fmt.Fprintf(h, "%d\n%s", len(part), part)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// runBatch converts every markdown file of a directory or glob pattern into a mirrored tree
+// of HTML pages in outputDir, then opens the page of the tree's README or index file
+func runBatch(inputPath, outputDir string, opts options) error {
+	if opts.watch {
+		return fmt.Errorf("--watch needs an input file, not a directory or pattern")
+	}
+	if outputDir == stdioPath {
+		return fmt.Errorf("a directory or pattern needs an output directory, not %s", stdioPath)
+	}
+
+	bc, err := batch.New(inputPath, opts.templateName)
+	if err != nil {
+		return err
+	}
+	bc.SetSelfContained(opts.selfContained)
+	bc.SetPreload(opts.preload)
+	bc.SetTOCSidebar(opts.toc)
+	bc.SetTOCDepth(opts.tocDepth)
+	bc.SetHighlight(opts.highlight)
+	bc.SetExtensions(opts.extensions)
+	bc.SetWorkers(opts.jobs)
+
+	finalOutputDir, err := output.GetOutputDir(outputDir)
+	if err != nil {
+		return fmt.Errorf("failed to determine output directory: %w", err)
+	}
+	// Converting a tree into itself works, but mixes the pages into the sources
+	if outInfo, err := os.Stat(finalOutputDir); err == nil {
+		if rootInfo, err := os.Stat(bc.RootDir()); err == nil && os.SameFile(outInfo, rootInfo) {
+			fmt.Fprintf(os.Stderr, "Warning: %s is the input and output directory, so the pages are written next to their markdown files\n", bc.RootDir())
+		}
+	}
+
+	fmt.Printf("Converting %d files from %s...\n", len(bc.Files()), bc.RootDir())
+	pages, err := bc.Convert(finalOutputDir)
+	fmt.Printf("Generated %d pages in %s\n", len(pages), finalOutputDir)
 	if err != nil {
-		return fmt.Errorf("failed to resolve input path: %w", err)
+		return err
 	}
 
+	if indexFile := bc.IndexFile(); indexFile != "" {
+		openOutput(bc.OutputPath(finalOutputDir, indexFile), opts)
+	}
+	return nil
+}
+
+// convertFile converts the input to finalOutputPath, choosing archive or single-file
+// conversion, and returns every source file the output was built from
+func convertFile(absInputPath, finalOutputPath string, opts options) ([]string, error) {
 	// If self-contained, check if document has links to other .md files
-	if selfContained {
+	// (markdown from stdin has no location to resolve them from)
+	if opts.selfContained && absInputPath != stdioPath {
 		hasMarkdownLinks, err := archive.HasMarkdownLinks(absInputPath)
 		if err != nil {
 			// Don't fail, just log warning and continue with single-file conversion
 			fmt.Fprintf(os.Stderr, "Warning: failed to check for markdown links: %v\n", err)
 		} else if hasMarkdownLinks {
 			// Use archive converter for multi-page archive
-			return runArchiveConversion(absInputPath, finalOutputPath, templateName, openBrowser, selfContained, preload, maxPages)
+			return runArchiveConversion(absInputPath, finalOutputPath, opts)
 		}
 	}
 
 	// Fall back to single-file conversion
-	return runSingleFileConversion(absInputPath, finalOutputPath, templateName, openBrowser, selfContained, preload)
+	return runSingleFileConversion(absInputPath, finalOutputPath, opts)
 }
 
-func runArchiveConversion(absInputPath, finalOutputPath, templateName string, openBrowser, selfContained, preload bool, maxPages int) error {
-	// Use archive writer helper function
-	err := archive.WriteArchive(absInputPath, finalOutputPath, templateName, maxPages, selfContained, preload)
+// openOutput opens the generated file in the browser if requested
+func openOutput(finalOutputPath string, opts options) {
+	if !opts.openBrowser || finalOutputPath == stdioPath {
+		return
+	}
+	if err := browser.OpenWith(opts.browser, finalOutputPath); err != nil {
+		// Don't fail on browser error, just warn
+		fmt.Fprintf(os.Stderr, "Warning: failed to open browser: %v\n", err)
+	}
+}
+
+// runWatch converts the input, then keeps regenerating the output whenever the input,
+// any page of the archive, or a referenced image changes. The generated page reloads
+// itself through the live reload sidecar script. Runs until interrupted.
+func runWatch(absInputPath, finalOutputPath string, opts options) error {
+	opts.reloadToken = watch.NewReloadToken()
+	buildStart := time.Now()
+	sources, err := convertFile(absInputPath, finalOutputPath, opts)
 	if err != nil {
 		return err
 	}
+	if err := watch.WriteReloadScript(finalOutputPath, opts.reloadToken); err != nil {
+		return err
+	}
+	defer watch.RemoveReloadScript(finalOutputPath)
+
+	openOutput(finalOutputPath, opts)
+
+	// Stop cleanly on Ctrl+C so the sidecar script is removed
+	stop := make(chan struct{})
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		<-interrupt
+		close(stop)
+	}()
+
+	// Files saved while converting are already newer than the output
+	w := watch.New(watch.DefaultInterval)
+	w.SetPathsSince(sources, buildStart)
+	fmt.Printf("Watching %d files for changes (press Ctrl+C to stop)...\n", len(sources))
+
+	for {
+		changed := w.Wait(stop)
+		if changed == nil {
+			return nil
+		}
+		for _, path := range changed {
+			fmt.Printf("Changed: %s\n", path)
+		}
 
-	// Print output path
-	fmt.Printf("Generated: %s\n", finalOutputPath)
+		opts.reloadToken = watch.NewReloadToken()
+		buildStart = time.Now()
+		newSources, err := convertFile(absInputPath, finalOutputPath, opts)
+		if err != nil {
+			// Keep watching - the user is probably mid-edit
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			continue
+		}
+		if err := watch.WriteReloadScript(finalOutputPath, opts.reloadToken); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
 
-	// Open in browser if requested
-	if openBrowser {
-		if err := browser.Open(finalOutputPath); err != nil {
-			// Don't fail on browser error, just warn
-			fmt.Fprintf(os.Stderr, "Warning: failed to open browser: %v\n", err)
+		// Links and images may have been added or removed
+		w.SetPathsSince(newSources, buildStart)
+	}
+}
+
+// collectSources returns the markdown files plus every local image they reference.
+// With preloaded, images are embedded by reading their whole directories, so each of
+// those directories and the files in it are included too: a file added, removed or
+// changed there is a change of the sources.
+func collectSources(mdPaths []string, preloaded bool) []string {
+	sources := append([]string{}, mdPaths...)
+	images := make(map[string]bool)
+	dirs := make(map[string]bool)
+	for _, mdPath := range mdPaths {
+		content, err := os.ReadFile(mdPath)
+		if err != nil {
+			continue
 		}
+		for _, image := range archive.ScanImageLinks(content, filepath.Dir(mdPath)) {
+			sources = append(sources, image)
+			images[image] = true
+			dirs[filepath.Dir(image)] = true
+		}
+	}
+	if !preloaded {
+		return sources
 	}
 
-	return nil
+	sortedDirs := make([]string, 0, len(dirs))
+	for dir := range dirs {
+		sortedDirs = append(sortedDirs, dir)
+	}
+	sort.Strings(sortedDirs)
+	for _, dir := range sortedDirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		// The directory's modification time changes when entries are added, removed or renamed
+		sources = append(sources, dir)
+		for _, entry := range entries {
+			if path := filepath.Join(dir, entry.Name()); !entry.IsDir() && !images[path] {
+				sources = append(sources, path)
+			}
+		}
+	}
+	return sources
 }
 
-func runSingleFileConversion(absInputPath, finalOutputPath, templateName string, openBrowser, selfContained, preload bool) error {
-	// Open input file for streaming read
-	inputFile, err := os.Open(absInputPath)
+func runArchiveConversion(absInputPath, finalOutputPath string, opts options) ([]string, error) {
+	graph, err := archive.BuildGraph(absInputPath, opts.maxPages)
 	if err != nil {
-		return fmt.Errorf("failed to open input file: %w", err)
+		return nil, fmt.Errorf("failed to build graph: %w", err)
+	}
+
+	fmt.Fprintf(statusOutput(finalOutputPath), "Building archive with %d pages...\n", graph.Count)
+
+	// Extract title from output filename (without extension)
+	var title string
+	if finalOutputPath != stdioPath {
+		outputBase := filepath.Base(finalOutputPath)
+		title = strings.TrimSuffix(outputBase, filepath.Ext(outputBase))
 	}
-	defer inputFile.Close()
 
-	// Get file size for optimal buffer pre-allocation
+	ac := archive.NewConverter(graph, opts.templateName, opts.selfContained, opts.preload, title)
+	ac.SetTOCSidebar(opts.toc)
+	ac.SetTOCDepth(opts.tocDepth)
+	ac.SetHighlight(opts.highlight)
+	ac.SetExtensions(opts.extensions)
+	ac.SetNav(opts.nav)
+	if opts.reloadToken != "" {
+		ac.SetLiveReload(filepath.Base(watch.ReloadScriptPath(finalOutputPath)), opts.reloadToken)
+	}
+	if finalOutputPath == stdioPath {
+		if err := ac.ConvertTo(os.Stdout); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := ac.ConvertToArchive(finalOutputPath); err != nil {
+			return nil, err
+		}
+
+		// Print output path
+		fmt.Printf("Generated: %s\n", finalOutputPath)
+	}
+
+	var pages []string
+	for _, node := range graph.OrderedNodes() {
+		pages = append(pages, node.Path)
+	}
+	return collectSources(pages, opts.selfContained && opts.preload), nil
+}
+
+func runSingleFileConversion(absInputPath, finalOutputPath string, opts options) ([]string, error) {
+	// Open input file for streaming read; markdown from stdin resolves paths against the working directory
+	inputFile := os.Stdin
+	baseDir, sourcePath := ".", ""
+	if absInputPath != stdioPath {
+		var err error
+		inputFile, err = os.Open(absInputPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open input file: %w", err)
+		}
+		defer inputFile.Close()
+		baseDir, sourcePath = filepath.Dir(absInputPath), absInputPath
+	}
+	baseDir, err := filepath.Abs(baseDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve base directory: %w", err)
+	}
+
+	// Get file size for optimal buffer pre-allocation (0 for pipes)
 	var fileSize int64
-	if stat, err := inputFile.Stat(); err == nil {
+	if stat, err := inputFile.Stat(); err == nil && stat.Mode().IsRegular() {
 		fileSize = stat.Size()
 	}
 
-	// Create output file for streaming write
-	outputFile, err := os.Create(finalOutputPath)
-	if err != nil {
-		return fmt.Errorf("failed to create output file: %w", err)
+	// Stream to stdout, or create output file for streaming write
+	outputFile := os.Stdout
+	if finalOutputPath != stdioPath {
+		outputFile, err = os.Create(finalOutputPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer outputFile.Close()
 	}
-	defer outputFile.Close()
 
 	// Create converter and perform conversion with size hint
 	conv := converter.New()
-	conv.SetBaseDir(filepath.Dir(absInputPath))
-	conv.SetSelfContained(selfContained)
-	conv.SetPreload(preload)
+	conv.SetBaseDir(baseDir)
+	conv.SetSourcePath(sourcePath)
+	conv.SetSelfContained(opts.selfContained)
+	conv.SetPreload(opts.preload)
+	conv.SetTOCSidebar(opts.toc)
+	conv.SetTOCDepth(opts.tocDepth)
+	conv.SetHighlight(opts.highlight)
+	conv.SetExtensions(opts.extensions)
 	// Set page title to output filename (without extension) for self-contained HTML
-	if selfContained {
+	if opts.selfContained && finalOutputPath != stdioPath {
 		outputBase := filepath.Base(finalOutputPath)
 		outputTitle := strings.TrimSuffix(outputBase, filepath.Ext(outputBase))
 		conv.SetTitle(outputTitle)
 	}
-	if err := conv.ConvertWithSize(inputFile, outputFile, templateName, fileSize); err != nil {
+	if opts.reloadToken != "" {
+		conv.SetLiveReload(filepath.Base(watch.ReloadScriptPath(finalOutputPath)), opts.reloadToken)
+	}
+	if err := conv.ConvertWithSize(inputFile, outputFile, opts.templateName, fileSize); err != nil {
 		// Clean up partial output file on error
-		outputFile.Close()
-		os.Remove(finalOutputPath)
-		return fmt.Errorf("conversion failed: %w", err)
+		if finalOutputPath != stdioPath {
+			outputFile.Close()
+			os.Remove(finalOutputPath)
+		}
+		return nil, fmt.Errorf("conversion failed: %w", err)
+	}
+
+	if finalOutputPath == stdioPath {
+		return nil, nil
 	}
 
 	// Ensure output is flushed
 	if err := outputFile.Sync(); err != nil {
-		return fmt.Errorf("failed to sync output file: %w", err)
+		return nil, fmt.Errorf("failed to sync output file: %w", err)
 	}
 
 	// Print output path
 	fmt.Printf("Generated: %s\n", finalOutputPath)
 
-	// Open in browser if requested
-	if openBrowser {
-		if err := browser.Open(finalOutputPath); err != nil {
-			// Don't fail on browser error, just warn
-			fmt.Fprintf(os.Stderr, "Warning: failed to open browser: %v\n", err)
-		}
+	if absInputPath == stdioPath {
+		return nil, nil
 	}
-
-	return nil
+	return collectSources([]string{absInputPath}, opts.selfContained && opts.preload), nil
 }
 
 func init() {
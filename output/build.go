@@ -0,0 +1,96 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// build records what an output file was generated from: a fingerprint of the settings
+// it was converted with, and the state of every source file when it was written
+type build struct {
+	Settings string        `json:"settings"`
+	Sources  []sourceState `json:"sources"`
+}
+
+// sourceState is the snapshot of a source file used to detect modifications
+type sourceState struct {
+	Path    string `json:"path"`
+	Size    int64  `json:"size"`    // -1 if the file didn't exist
+	ModTime int64  `json:"modTime"` // Unix nanoseconds
+}
+
+// BuildPath returns the path of the build record written next to outputPath
+func BuildPath(outputPath string) string {
+	return outputPath + ".build.json"
+}
+
+// statSource returns the current state of a source file
+func statSource(path string) sourceState {
+	info, err := os.Stat(path)
+	if err != nil {
+		return sourceState{Path: path, Size: -1}
+	}
+	return sourceState{Path: path, Size: info.Size(), ModTime: info.ModTime().UnixNano()}
+}
+
+// WriteBuild records that outputPath was just generated from sources (the markdown files
+// and the images they reference) with settings, a fingerprint of everything else that
+// affects the output, such as the options and the template.
+func WriteBuild(outputPath, settings string, sources []string) error {
+	b := build{Settings: settings}
+	for _, source := range sources {
+		b.Sources = append(b.Sources, statSource(source))
+	}
+
+	data, err := json.Marshal(b)
+	if err != nil {
+		return fmt.Errorf("failed to encode build record: %w", err)
+	}
+	if err := os.WriteFile(BuildPath(outputPath), data, 0644); err != nil {
+		return fmt.Errorf("failed to write build record: %w", err)
+	}
+	return nil
+}
+
+// RemoveBuild removes the build record of outputPath, so the output is no longer
+// considered up to date, e.g. before it is regenerated in a different way
+func RemoveBuild(outputPath string) error {
+	if err := os.Remove(BuildPath(outputPath)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove build record: %w", err)
+	}
+	return nil
+}
+
+// UpToDate reports whether outputPath exists and was generated with settings from
+// sources that haven't changed since, according to its build record. An up-to-date
+// output is marked as recently used, so the cleanup keeps it.
+func UpToDate(outputPath, settings string) bool {
+	if _, err := os.Stat(outputPath); err != nil {
+		return false
+	}
+
+	data, err := os.ReadFile(BuildPath(outputPath))
+	if err != nil {
+		return false
+	}
+	var b build
+	if err := json.Unmarshal(data, &b); err != nil {
+		return false
+	}
+
+	if settings == "" || b.Settings != settings || len(b.Sources) == 0 {
+		return false
+	}
+	for _, recorded := range b.Sources {
+		if statSource(recorded.Path) != recorded {
+			return false
+		}
+	}
+
+	now := time.Now()
+	os.Chtimes(outputPath, now, now)
+	os.Chtimes(BuildPath(outputPath), now, now)
+	return true
+}
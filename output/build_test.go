@@ -0,0 +1,126 @@
+package output
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// setupBuild creates an output file and two sources and records the build
+func setupBuild(t *testing.T) (outputPath, mdPath, imagePath string) {
+	t.Helper()
+	dir := t.TempDir()
+	outputPath = filepath.Join(dir, "0123456789abcdef.html")
+	mdPath = filepath.Join(dir, "doc.md")
+	imagePath = filepath.Join(dir, "image.png")
+	for _, path := range []string{outputPath, mdPath, imagePath} {
+		if err := os.WriteFile(path, []byte("content"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := WriteBuild(outputPath, "settings", []string{mdPath, imagePath}); err != nil {
+		t.Fatalf("WriteBuild failed: %v", err)
+	}
+	return outputPath, mdPath, imagePath
+}
+
+func TestUpToDate_Unchanged(t *testing.T) {
+	outputPath, _, _ := setupBuild(t)
+
+	if !UpToDate(outputPath, "settings") {
+		t.Error("expected output to be up to date")
+	}
+}
+
+func TestUpToDate_MarksOutputAsUsed(t *testing.T) {
+	outputPath, _, _ := setupBuild(t)
+	old := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(outputPath, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	if !UpToDate(outputPath, "settings") {
+		t.Fatal("expected output to be up to date")
+	}
+	if info, err := os.Stat(outputPath); err != nil || time.Since(info.ModTime()) > time.Hour {
+		t.Error("expected the reused output to count as recent")
+	}
+}
+
+func TestUpToDate_SourceChanged(t *testing.T) {
+	outputPath, _, imagePath := setupBuild(t)
+
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(imagePath, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if UpToDate(outputPath, "settings") {
+		t.Error("expected a modified image to make the output stale")
+	}
+}
+
+func TestUpToDate_SourceRemoved(t *testing.T) {
+	outputPath, mdPath, _ := setupBuild(t)
+
+	if err := os.Remove(mdPath); err != nil {
+		t.Fatal(err)
+	}
+	if UpToDate(outputPath, "settings") {
+		t.Error("expected a removed source to make the output stale")
+	}
+}
+
+func TestUpToDate_MissingSourceAppears(t *testing.T) {
+	dir := t.TempDir()
+	outputPath := filepath.Join(dir, "out.html")
+	missing := filepath.Join(dir, "missing.png")
+	if err := os.WriteFile(outputPath, []byte("content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteBuild(outputPath, "settings", []string{missing}); err != nil {
+		t.Fatalf("WriteBuild failed: %v", err)
+	}
+	if !UpToDate(outputPath, "settings") {
+		t.Fatal("expected output to be up to date while the image is still missing")
+	}
+
+	if err := os.WriteFile(missing, []byte("png"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if UpToDate(outputPath, "settings") {
+		t.Error("expected a newly created image to make the output stale")
+	}
+}
+
+func TestUpToDate_Stale(t *testing.T) {
+	outputPath, _, _ := setupBuild(t)
+
+	if UpToDate(outputPath, "other settings") {
+		t.Error("expected different settings to make the output stale")
+	}
+	if UpToDate(outputPath, "") {
+		t.Error("expected unknown settings never to be up to date")
+	}
+
+	if err := RemoveBuild(outputPath); err != nil {
+		t.Fatalf("RemoveBuild failed: %v", err)
+	}
+	if UpToDate(outputPath, "settings") {
+		t.Error("expected output without a build record to be stale")
+	}
+	if err := RemoveBuild(outputPath); err != nil {
+		t.Errorf("expected removing a missing build record to succeed, got: %v", err)
+	}
+}
+
+func TestUpToDate_OutputRemoved(t *testing.T) {
+	outputPath, _, _ := setupBuild(t)
+
+	if err := os.Remove(outputPath); err != nil {
+		t.Fatal(err)
+	}
+	if UpToDate(outputPath, "settings") {
+		t.Error("expected a removed output to need regenerating")
+	}
+}
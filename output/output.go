@@ -2,10 +2,17 @@ package output
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
 )
 
 const appName = "mdview"
@@ -41,6 +48,56 @@ func GetOutputPath(specifiedPath string) (string, error) {
 	return filepath.Join(appDir, filename), nil
 }
 
+// GetOutputDir returns the output directory for a batch conversion.
+// If specifiedDir is non-empty, it returns that directory (creating it if needed).
+// Otherwise, it creates a randomly named directory in %LocalAppData%/mdview/
+func GetOutputDir(specifiedDir string) (string, error) {
+	dir := specifiedDir
+	if dir == "" {
+		appDir, err := getAppDataDir()
+		if err != nil {
+			return "", err
+		}
+
+		randomBytes := make([]byte, 8)
+		if _, err := rand.Read(randomBytes); err != nil {
+			return "", fmt.Errorf("failed to generate random directory name: %w", err)
+		}
+		dir = filepath.Join(appDir, hex.EncodeToString(randomBytes))
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create output directory: %w", err)
+	}
+	return dir, nil
+}
+
+// GetOutputPathFor returns the output path for the HTML file converted from inputPath.
+// If specifiedPath is non-empty, it is used as in GetOutputPath. Otherwise the file in
+// %LocalAppData%/mdview/ is named after a hash of inputPath, so converting the same
+// input again overwrites the same file instead of adding another one. An empty
+// inputPath (e.g. markdown from stdin) gets a random filename.
+func GetOutputPathFor(specifiedPath, inputPath string) (string, error) {
+	if specifiedPath != "" || inputPath == "" {
+		return GetOutputPath(specifiedPath)
+	}
+
+	appDir, err := getAppDataDir()
+	if err != nil {
+		return "", err
+	}
+
+	// Paths that differ only in case name the same file on Windows
+	key := filepath.Clean(inputPath)
+	if runtime.GOOS == "windows" {
+		key = strings.ToLower(key)
+	}
+	sum := sha256.Sum256([]byte(key))
+	filename := hex.EncodeToString(sum[:8]) + ".html"
+
+	return filepath.Join(appDir, filename), nil
+}
+
 // getAppDataDir returns the application data directory, creating it if needed
 func getAppDataDir() (string, error) {
 	// Use LocalAppData on Windows
@@ -58,24 +115,123 @@ func getAppDataDir() (string, error) {
 	return appDir, nil
 }
 
-// CleanupOldFiles removes HTML files older than the specified age from the app data directory.
-// This is optional and can be called to prevent accumulation of temp files.
-func CleanupOldFiles(maxAgeSeconds int64) error {
+// Retention defaults for the opportunistic cleanup after a conversion
+const (
+	DefaultMaxAge  = 7 * 24 * time.Hour
+	DefaultMaxSize = 200 << 20 // 200 MB
+)
+
+// gracePeriod protects recently written output from the size limit: a browser may
+// still be about to load it. Output of running watch sessions is kept regardless.
+const gracePeriod = time.Hour
+
+// generatedNamePattern matches the entries GetOutputPath and GetOutputDir create: HTML
+// files, their live reload sidecar scripts, build records and watch session heartbeats,
+// and batch output directories
+var generatedNamePattern = regexp.MustCompile(`^[0-9a-f]{16}(\.html(\.reload\.js|\.build\.json|\.session)?)?$`)
+
+// RemovedFile describes an entry deleted from the app data directory
+type RemovedFile struct {
+	Path string
+	Size int64 // Total size, including the contents of a directory
+}
+
+// generatedEntry is an entry of the app data directory created by mdview
+type generatedEntry struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// CleanupOldFiles removes generated files and directories from the app data directory
+// that are older than maxAge, then the oldest remaining ones until they take up at most
+// maxSize bytes. A zero maxAge or maxSize disables that limit. Entries modified in the
+// last hour are never removed for size, so a page that was just generated survives, and
+// the output of a running watch session (see StartSession) is never removed at all.
+// Other files in the directory are left alone. Entries that can't be removed are
+// skipped and reported in the returned error.
+func CleanupOldFiles(maxAge time.Duration, maxSize int64) ([]RemovedFile, error) {
+	entries, err := listGenerated()
+	if err != nil {
+		return nil, err
+	}
+
+	// Oldest first, so the size limit removes the least recently generated output
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].modTime.Before(entries[j].modTime)
+	})
+
+	var total int64
+	for _, e := range entries {
+		total += e.size
+	}
+
+	now := time.Now()
+	live := liveSessions(entries, now)
+	var removed []RemovedFile
+	var errs []error
+	for _, e := range entries {
+		if live[outputOf(e.path)] {
+			continue
+		}
+		age := now.Sub(e.modTime)
+		expired := maxAge > 0 && age > maxAge
+		overSize := maxSize > 0 && total > maxSize && age > gracePeriod
+		if !expired && !overSize {
+			continue
+		}
+		if err := os.RemoveAll(e.path); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		total -= e.size
+		removed = append(removed, RemovedFile{Path: e.path, Size: e.size})
+	}
+
+	return removed, errors.Join(errs...)
+}
+
+// Purge removes every generated file and directory from the app data directory,
+// regardless of age. Other files in the directory are left alone.
+func Purge() ([]RemovedFile, error) {
+	entries, err := listGenerated()
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []RemovedFile
+	var errs []error
+	for _, e := range entries {
+		if err := os.RemoveAll(e.path); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		removed = append(removed, RemovedFile{Path: e.path, Size: e.size})
+	}
+
+	return removed, errors.Join(errs...)
+}
+
+// listGenerated returns the entries of the app data directory that mdview created
+func listGenerated() ([]generatedEntry, error) {
 	appDir, err := getAppDataDir()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	entries, err := os.ReadDir(appDir)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("failed to read app data directory: %w", err)
 	}
 
+	var result []generatedEntry
 	for _, entry := range entries {
-		if entry.IsDir() {
+		if !generatedNamePattern.MatchString(entry.Name()) {
 			continue
 		}
-		if filepath.Ext(entry.Name()) != ".html" {
+		// Directories are batch output (a bare name), files are pages or sidecars (an extension)
+		isBatchDir := filepath.Ext(entry.Name()) == ""
+		if entry.IsDir() != isBatchDir {
 			continue
 		}
 
@@ -84,11 +240,31 @@ func CleanupOldFiles(maxAgeSeconds int64) error {
 			continue
 		}
 
-		// Check if file is old enough to delete
-		// Note: Using ModTime as a simple age check
-		// In a production app, you might want to track file creation time separately
-		_ = info // Placeholder for age check logic
+		e := generatedEntry{
+			path:    filepath.Join(appDir, entry.Name()),
+			size:    info.Size(),
+			modTime: info.ModTime(),
+		}
+		if entry.IsDir() {
+			e.size = dirSize(e.path)
+		}
+		result = append(result, e)
 	}
 
-	return nil
+	return result, nil
+}
+
+// dirSize returns the total size of the files under dir
+func dirSize(dir string) int64 {
+	var size int64
+	filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info, err := d.Info(); err == nil && info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
 }
@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestGetOutputPathWithSpecifiedPath(t *testing.T) {
@@ -94,6 +95,81 @@ func TestGetOutputPathRandomFilenameLength(t *testing.T) {
 	}
 }
 
+func TestGetOutputPathForIsStablePerInput(t *testing.T) {
+	t.Setenv("LOCALAPPDATA", t.TempDir())
+
+	first, err := GetOutputPathFor("", "/docs/guide.md")
+	if err != nil {
+		t.Fatalf("GetOutputPathFor failed: %v", err)
+	}
+	again, _ := GetOutputPathFor("", "/docs/./guide.md")
+	other, _ := GetOutputPathFor("", "/docs/other.md")
+
+	if first != again {
+		t.Errorf("expected the same file for the same input, got %q and %q", first, again)
+	}
+	if first == other {
+		t.Error("expected different files for different inputs")
+	}
+	if !generatedNamePattern.MatchString(filepath.Base(first)) {
+		t.Errorf("expected a name the cleanup recognizes, got %q", filepath.Base(first))
+	}
+}
+
+func TestGetOutputPathForSpecifiedOrNoInput(t *testing.T) {
+	t.Setenv("LOCALAPPDATA", t.TempDir())
+
+	specifiedPath := filepath.Join(t.TempDir(), "out.html")
+	if result, _ := GetOutputPathFor(specifiedPath, "/docs/guide.md"); result != specifiedPath {
+		t.Errorf("expected %q, got %q", specifiedPath, result)
+	}
+
+	// Without an input path, e.g. for stdin, every call gets a new file
+	result1, _ := GetOutputPathFor("", "")
+	result2, _ := GetOutputPathFor("", "")
+	if result1 == result2 {
+		t.Error("expected different random filenames without an input path")
+	}
+}
+
+func TestGetOutputDirWithSpecifiedDir(t *testing.T) {
+	specifiedDir := filepath.Join(t.TempDir(), "site", "docs")
+	result, err := GetOutputDir(specifiedDir)
+	if err != nil {
+		t.Fatalf("GetOutputDir failed: %v", err)
+	}
+
+	if result != specifiedDir {
+		t.Errorf("expected %q, got %q", specifiedDir, result)
+	}
+	if info, err := os.Stat(specifiedDir); err != nil || !info.IsDir() {
+		t.Error("expected output directory to be created")
+	}
+}
+
+func TestGetOutputDirGeneratesRandomDir(t *testing.T) {
+	t.Setenv("LOCALAPPDATA", t.TempDir())
+
+	result1, err := GetOutputDir("")
+	if err != nil {
+		t.Fatalf("GetOutputDir failed: %v", err)
+	}
+	result2, err := GetOutputDir("")
+	if err != nil {
+		t.Fatalf("GetOutputDir failed: %v", err)
+	}
+
+	if result1 == result2 {
+		t.Error("expected different random directories for each call")
+	}
+	if filepath.Base(filepath.Dir(result1)) != "mdview" {
+		t.Errorf("expected directory inside mdview, got %q", result1)
+	}
+	if info, err := os.Stat(result1); err != nil || !info.IsDir() {
+		t.Error("expected output directory to be created")
+	}
+}
+
 func BenchmarkGetOutputPathSpecified(b *testing.B) {
 	dir, _ := os.MkdirTemp("", "mdview-bench-*")
 	defer os.RemoveAll(dir)
@@ -110,3 +186,141 @@ func BenchmarkGetOutputPathRandom(b *testing.B) {
 		_, _ = GetOutputPath("")
 	}
 }
+
+// createGenerated creates a generated entry of the given size in the app data
+// directory, last modified age ago. Names without an extension become directories.
+func createGenerated(t *testing.T, appDir, name string, size int, age time.Duration) string {
+	t.Helper()
+	path := filepath.Join(appDir, name)
+	filePath := path
+	if filepath.Ext(name) == "" {
+		if err := os.MkdirAll(path, 0755); err != nil {
+			t.Fatal(err)
+		}
+		filePath = filepath.Join(path, "index.html")
+	}
+	if err := os.WriteFile(filePath, make([]byte, size), 0644); err != nil {
+		t.Fatal(err)
+	}
+	modTime := time.Now().Add(-age)
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// setupAppDir points the app data directory at a temporary directory and returns it
+func setupAppDir(t *testing.T) string {
+	t.Helper()
+	localAppData := t.TempDir()
+	t.Setenv("LOCALAPPDATA", localAppData)
+	appDir := filepath.Join(localAppData, "mdview")
+	if err := os.MkdirAll(appDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	return appDir
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func TestCleanupOldFilesByAge(t *testing.T) {
+	appDir := setupAppDir(t)
+	day := 24 * time.Hour
+	old := createGenerated(t, appDir, "0123456789abcdef.html", 10, 10*day)
+	oldSidecar := createGenerated(t, appDir, "0123456789abcdef.html.reload.js", 10, 10*day)
+	oldBuild := createGenerated(t, appDir, "0123456789abcdef.html.build.json", 10, 10*day)
+	oldDir := createGenerated(t, appDir, "00000000000000aa", 10, 10*day)
+	recent := createGenerated(t, appDir, "fedcba9876543210.html", 10, day)
+	foreign := createGenerated(t, appDir, "notes.html", 10, 10*day)
+
+	removed, err := CleanupOldFiles(7*day, 0)
+	if err != nil {
+		t.Fatalf("CleanupOldFiles failed: %v", err)
+	}
+
+	if len(removed) != 4 {
+		t.Errorf("expected 4 removed entries, got %v", removed)
+	}
+	for _, path := range []string{old, oldSidecar, oldBuild, oldDir} {
+		if exists(path) {
+			t.Errorf("expected %s to be removed", path)
+		}
+	}
+	for _, path := range []string{recent, foreign} {
+		if !exists(path) {
+			t.Errorf("expected %s to be kept", path)
+		}
+	}
+}
+
+func TestCleanupOldFilesBySize(t *testing.T) {
+	appDir := setupAppDir(t)
+	oldest := createGenerated(t, appDir, "000000000000000a.html", 100, 5*time.Hour)
+	middle := createGenerated(t, appDir, "000000000000000b", 100, 4*time.Hour)
+	newest := createGenerated(t, appDir, "000000000000000c.html", 100, 3*time.Hour)
+	fresh := createGenerated(t, appDir, "000000000000000d.html", 500, time.Minute)
+
+	removed, err := CleanupOldFiles(0, 250)
+	if err != nil {
+		t.Fatalf("CleanupOldFiles failed: %v", err)
+	}
+
+	// Everything outside the grace period goes, oldest first, but fresh output is kept
+	// even though it exceeds the limit on its own
+	if len(removed) != 3 || removed[0].Path != oldest || removed[1].Path != middle || removed[1].Size != 100 {
+		t.Errorf("expected the oldest entries to be removed in order, got %v", removed)
+	}
+	if exists(newest) {
+		t.Error("expected entries to be removed until within the limit")
+	}
+	if !exists(fresh) {
+		t.Error("expected output from the last hour to be kept")
+	}
+}
+
+func TestCleanupOldFilesWithinLimits(t *testing.T) {
+	appDir := setupAppDir(t)
+	path := createGenerated(t, appDir, "0123456789abcdef.html", 100, 2*time.Hour)
+
+	removed, err := CleanupOldFiles(DefaultMaxAge, DefaultMaxSize)
+	if err != nil {
+		t.Fatalf("CleanupOldFiles failed: %v", err)
+	}
+	if len(removed) != 0 || !exists(path) {
+		t.Errorf("expected nothing to be removed, got %v", removed)
+	}
+}
+
+func TestPurge(t *testing.T) {
+	appDir := setupAppDir(t)
+	page := createGenerated(t, appDir, "0123456789abcdef.html", 10, 0)
+	dir := createGenerated(t, appDir, "0123456789abcdef", 20, 0)
+	foreign := createGenerated(t, appDir, "keep", 10, 0)
+	// A directory named like a page isn't something mdview created
+	notBatchDir := filepath.Join(appDir, "00000000000000ff.html")
+	if err := os.MkdirAll(notBatchDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := Purge()
+	if err != nil {
+		t.Fatalf("Purge failed: %v", err)
+	}
+
+	var total int64
+	for _, file := range removed {
+		total += file.Size
+	}
+	if len(removed) != 2 || total != 30 {
+		t.Errorf("expected 2 entries totalling 30 bytes, got %v", removed)
+	}
+	if exists(page) || exists(dir) {
+		t.Error("expected generated output to be removed")
+	}
+	if !exists(foreign) || !exists(notBatchDir) {
+		t.Error("expected other entries to be kept")
+	}
+}
@@ -0,0 +1,72 @@
+package output
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Watch sessions keep their output in use: a heartbeat file next to it is touched every
+// heartbeatInterval, and the cleanup leaves an output alone while its heartbeat is fresh.
+// A session that died without removing its heartbeat goes stale after heartbeatTimeout.
+const (
+	heartbeatInterval = time.Minute
+	heartbeatTimeout  = 3 * heartbeatInterval
+)
+
+// sessionSuffix is appended to an output path for the heartbeat file of its watch session
+const sessionSuffix = ".session"
+
+// SessionPath returns the path of the heartbeat file of a watch session on outputPath
+func SessionPath(outputPath string) string {
+	return outputPath + sessionSuffix
+}
+
+// StartSession marks outputPath as in use by a running watch session, so the cleanup of
+// other runs keeps it however old or large it is. The returned function ends the session.
+func StartSession(outputPath string) (stop func(), err error) {
+	path := SessionPath(outputPath)
+	if err := os.WriteFile(path, []byte(fmt.Sprintf("%d\n", os.Getpid())), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write session file: %w", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(heartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				os.Chtimes(path, now, now)
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		os.Remove(path)
+	}, nil
+}
+
+// liveSessions returns the output paths of the running watch sessions among entries
+func liveSessions(entries []generatedEntry, now time.Time) map[string]bool {
+	live := make(map[string]bool)
+	for _, e := range entries {
+		if strings.HasSuffix(e.path, sessionSuffix) && now.Sub(e.modTime) < heartbeatTimeout {
+			live[strings.TrimSuffix(e.path, sessionSuffix)] = true
+		}
+	}
+	return live
+}
+
+// outputOf returns the output path an entry belongs to: the page of a sidecar, build
+// record or heartbeat file, and the entry itself otherwise
+func outputOf(path string) string {
+	if i := strings.Index(path, ".html."); i != -1 {
+		return path[:i+len(".html")]
+	}
+	return path
+}
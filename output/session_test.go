@@ -0,0 +1,54 @@
+package output
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStartSession(t *testing.T) {
+	appDir := setupAppDir(t)
+	page := createGenerated(t, appDir, "0123456789abcdef.html", 10, time.Hour)
+
+	stop, err := StartSession(page)
+	if err != nil {
+		t.Fatalf("StartSession failed: %v", err)
+	}
+	if !exists(SessionPath(page)) {
+		t.Fatal("expected a session file next to the page")
+	}
+
+	stop()
+	if exists(SessionPath(page)) {
+		t.Error("expected the session file to be removed when the session stops")
+	}
+}
+
+func TestCleanupOldFilesKeepsWatchedOutput(t *testing.T) {
+	appDir := setupAppDir(t)
+	day := 24 * time.Hour
+	watched := createGenerated(t, appDir, "000000000000000a.html", 100, 10*day)
+	watchedSidecar := createGenerated(t, appDir, "000000000000000a.html.reload.js", 10, 10*day)
+	createGenerated(t, appDir, "000000000000000a.html.session", 0, time.Minute)
+	// A session that died without removing its heartbeat doesn't protect its page
+	abandoned := createGenerated(t, appDir, "000000000000000b.html", 100, 5*time.Hour)
+	abandonedSession := createGenerated(t, appDir, "000000000000000b.html.session", 0, 5*time.Hour)
+
+	removed, err := CleanupOldFiles(7*day, 50)
+	if err != nil {
+		t.Fatalf("CleanupOldFiles failed: %v", err)
+	}
+
+	for _, path := range []string{watched, watchedSidecar} {
+		if !exists(path) {
+			t.Errorf("expected %s of a running watch session to be kept", path)
+		}
+	}
+	for _, path := range []string{abandoned, abandonedSession} {
+		if exists(path) {
+			t.Errorf("expected %s of a stale session to be removed", path)
+		}
+	}
+	if len(removed) != 2 {
+		t.Errorf("expected 2 removed entries, got %v", removed)
+	}
+}
@@ -0,0 +1,272 @@
+package register
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	desktopFile  = appName + ".desktop"
+	mimeAppsFile = "mimeapps.list"
+)
+
+// mimeTypes are the MIME types mdview is made the default for. text/x-markdown is
+// still used by some desktops and shared-mime-info versions.
+var mimeTypes = []string{"text/markdown", "text/x-markdown"}
+
+// Register sets mdview as the default program for .md files.
+// Writes a desktop entry to $XDG_DATA_HOME/applications and makes it the default
+// for markdown in $XDG_CONFIG_HOME/mimeapps.list, so no root privileges are required.
+func Register() error {
+	// Get the absolute path to the current executable
+	exePath, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to get executable path: %w", err)
+	}
+	exePath, err = filepath.Abs(exePath)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path: %w", err)
+	}
+
+	dataDir, err := xdgDir("XDG_DATA_HOME", ".local/share")
+	if err != nil {
+		return err
+	}
+	configDir, err := xdgDir("XDG_CONFIG_HOME", ".config")
+	if err != nil {
+		return err
+	}
+
+	// Write the desktop entry: $XDG_DATA_HOME/applications/mdview.desktop
+	appsDir := filepath.Join(dataDir, "applications")
+	if err := os.MkdirAll(appsDir, 0755); err != nil {
+		return fmt.Errorf("failed to create applications directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(appsDir, desktopFile), []byte(desktopEntry(exePath)), 0644); err != nil {
+		return fmt.Errorf("failed to write desktop entry: %w", err)
+	}
+
+	// Make it the default for markdown files
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	err = updateMimeApps(filepath.Join(configDir, mimeAppsFile), func(section string, apps []string) []string {
+		switch section {
+		case "Default Applications":
+			return []string{desktopFile}
+		case "Added Associations":
+			return append([]string{desktopFile}, removeApp(apps, desktopFile)...)
+		}
+		return apps
+	})
+	if err != nil {
+		return err
+	}
+
+	refreshDesktopDatabase(appsDir)
+	return nil
+}
+
+// Unregister removes mdview as the default program for .md files.
+func Unregister() error {
+	dataDir, err := xdgDir("XDG_DATA_HOME", ".local/share")
+	if err != nil {
+		return err
+	}
+	configDir, err := xdgDir("XDG_CONFIG_HOME", ".config")
+	if err != nil {
+		return err
+	}
+
+	appsDir := filepath.Join(dataDir, "applications")
+	if err := os.Remove(filepath.Join(appsDir, desktopFile)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove desktop entry: %w", err)
+	}
+
+	// Remove the associations that point to us
+	mimeApps := filepath.Join(configDir, mimeAppsFile)
+	if _, err := os.Stat(mimeApps); err == nil {
+		err := updateMimeApps(mimeApps, func(section string, apps []string) []string {
+			return removeApp(apps, desktopFile)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	refreshDesktopDatabase(appsDir)
+	return nil
+}
+
+// xdgDir returns the directory named by an XDG environment variable, or its
+// default below the home directory when the variable is unset or not absolute
+func xdgDir(envVar, homeDefault string) (string, error) {
+	if dir := os.Getenv(envVar); filepath.IsAbs(dir) {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, homeDefault), nil
+}
+
+// desktopEntry returns the contents of the desktop entry that opens files with exePath
+func desktopEntry(exePath string) string {
+	return "[Desktop Entry]\n" +
+		"Type=Application\n" +
+		"Name=" + appName + "\n" +
+		"Comment=" + description + "\n" +
+		"Exec=" + quoteExec(exePath) + " %f\n" +
+		"MimeType=" + strings.Join(mimeTypes, ";") + ";\n" +
+		"Categories=Utility;TextTools;\n" +
+		"Terminal=false\n" +
+		"NoDisplay=true\n"
+}
+
+// quoteExec quotes a path for the Exec key of a desktop entry. Inside the quotes,
+// ", `, $ and \ are escaped with a backslash, and every backslash is then doubled
+// again because Exec is also a string value.
+func quoteExec(path string) string {
+	quoted := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "`", "\\`", `$`, `\$`).Replace(path)
+	quoted = strings.ReplaceAll(`"`+quoted+`"`, `\`, `\\`)
+	return strings.ReplaceAll(quoted, "%", "%%")
+}
+
+// updateMimeApps rewrites the markdown entries of a mimeapps.list file.
+// edit is called for each of the [Default Applications] and [Added Associations]
+// sections with the desktop files currently listed for a markdown MIME type, and
+// returns the new list; an empty list removes the entry. Everything else in the
+// file is kept as is.
+func updateMimeApps(path string, edit func(section string, apps []string) []string) error {
+	var lines []string
+	if data, err := os.ReadFile(path); err == nil {
+		scanner := bufio.NewScanner(strings.NewReader(string(data)))
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %w", mimeAppsFile, err)
+	}
+
+	for _, section := range []string{"Default Applications", "Added Associations"} {
+		lines = editMimeSection(lines, section, func(apps []string) []string {
+			return edit(section, apps)
+		})
+	}
+	content := strings.Join(lines, "\n")
+	if content != "" {
+		content += "\n"
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", mimeAppsFile, err)
+	}
+	return nil
+}
+
+// editMimeSection applies edit to the markdown MIME types in one section of the
+// lines of a mimeapps.list file, adding the section and keys as needed
+func editMimeSection(lines []string, section string, edit func(apps []string) []string) []string {
+	header := "[" + section + "]"
+
+	// Find the section, or add it at the end
+	original := lines
+	start := -1
+	for i, line := range lines {
+		if strings.TrimSpace(line) == header {
+			start = i
+			break
+		}
+	}
+	added := start == -1
+	if added {
+		lines = append([]string(nil), lines...)
+		if len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) != "" {
+			lines = append(lines, "")
+		}
+		lines = append(lines, header)
+		start = len(lines) - 1
+	}
+	end := len(lines)
+	for i := start + 1; i < len(lines); i++ {
+		if strings.HasPrefix(strings.TrimSpace(lines[i]), "[") {
+			end = i
+			break
+		}
+	}
+
+	body := append([]string(nil), lines[start+1:end]...)
+	for _, mimeType := range mimeTypes {
+		index := -1
+		var apps []string
+		for i, line := range body {
+			key, value, ok := strings.Cut(line, "=")
+			if ok && strings.TrimSpace(key) == mimeType {
+				index = i
+				apps = splitApps(value)
+				break
+			}
+		}
+
+		apps = edit(apps)
+		switch {
+		case len(apps) == 0 && index >= 0:
+			body = append(body[:index], body[index+1:]...)
+		case len(apps) == 0:
+		case index >= 0:
+			body[index] = mimeType + "=" + strings.Join(apps, ";") + ";"
+		default:
+			// Insert after the section's last entry, before any trailing blank lines
+			at := len(body)
+			for at > 0 && strings.TrimSpace(body[at-1]) == "" {
+				at--
+			}
+			entry := mimeType + "=" + strings.Join(apps, ";") + ";"
+			body = append(body[:at], append([]string{entry}, body[at:]...)...)
+		}
+	}
+
+	// Don't leave an empty section behind when there was nothing to add
+	if added && len(body) == 0 {
+		return original
+	}
+
+	result := append([]string(nil), lines[:start+1]...)
+	result = append(result, body...)
+	return append(result, lines[end:]...)
+}
+
+// splitApps splits a semicolon-separated list of desktop files
+func splitApps(value string) []string {
+	var apps []string
+	for _, app := range strings.Split(value, ";") {
+		if app = strings.TrimSpace(app); app != "" {
+			apps = append(apps, app)
+		}
+	}
+	return apps
+}
+
+// removeApp returns apps without app
+func removeApp(apps []string, app string) []string {
+	var result []string
+	for _, a := range apps {
+		if a != app {
+			result = append(result, a)
+		}
+	}
+	return result
+}
+
+// refreshDesktopDatabase updates the MIME cache of the applications directory,
+// if update-desktop-database is installed. Desktops that read mimeapps.list
+// directly don't need it, so failures are ignored.
+func refreshDesktopDatabase(appsDir string) {
+	if path, err := exec.LookPath("update-desktop-database"); err == nil {
+		_ = exec.Command(path, appsDir).Run()
+	}
+}
@@ -0,0 +1,159 @@
+package register
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setupHome points HOME at a temporary directory with the XDG variables unset
+func setupHome(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_DATA_HOME", "")
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("PATH", "") // Keep update-desktop-database away from the temp HOME
+	return home
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", path, err)
+	}
+	return string(data)
+}
+
+func TestRegister_WritesDesktopEntryAndDefaults(t *testing.T) {
+	home := setupHome(t)
+
+	if err := Register(); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+
+	entry := readFile(t, filepath.Join(home, ".local/share/applications/mdview.desktop"))
+	exePath, _ := os.Executable()
+	wants := []string{
+		"[Desktop Entry]\n",
+		"Type=Application\n",
+		"MimeType=text/markdown;text/x-markdown;\n",
+		"Exec=" + quoteExec(exePath) + " %f\n",
+	}
+	for _, want := range wants {
+		if !strings.Contains(entry, want) {
+			t.Errorf("expected %q in desktop entry, got:\n%s", want, entry)
+		}
+	}
+
+	mimeApps := readFile(t, filepath.Join(home, ".config/mimeapps.list"))
+	want := "[Default Applications]\ntext/markdown=mdview.desktop;\ntext/x-markdown=mdview.desktop;\n\n" +
+		"[Added Associations]\ntext/markdown=mdview.desktop;\ntext/x-markdown=mdview.desktop;\n"
+	if mimeApps != want {
+		t.Errorf("unexpected mimeapps.list:\n%s", mimeApps)
+	}
+}
+
+func TestRegister_UsesXDGVariables(t *testing.T) {
+	home := setupHome(t)
+	dataHome := filepath.Join(home, "data")
+	configHome := filepath.Join(home, "config")
+	t.Setenv("XDG_DATA_HOME", dataHome)
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+
+	if err := Register(); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dataHome, "applications/mdview.desktop")); err != nil {
+		t.Errorf("expected desktop entry under $XDG_DATA_HOME: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(configHome, "mimeapps.list")); err != nil {
+		t.Errorf("expected mimeapps.list under $XDG_CONFIG_HOME: %v", err)
+	}
+}
+
+func TestRegister_KeepsOtherEntries(t *testing.T) {
+	home := setupHome(t)
+	mimeAppsPath := filepath.Join(home, ".config/mimeapps.list")
+	if err := os.MkdirAll(filepath.Dir(mimeAppsPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	existing := "[Default Applications]\ntext/html=firefox.desktop;\ntext/markdown=gedit.desktop;\n\n" +
+		"[Added Associations]\ntext/markdown=gedit.desktop;\n"
+	if err := os.WriteFile(mimeAppsPath, []byte(existing), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Registering twice must not duplicate anything
+	for i := 0; i < 2; i++ {
+		if err := Register(); err != nil {
+			t.Fatalf("Register failed: %v", err)
+		}
+	}
+
+	want := "[Default Applications]\ntext/html=firefox.desktop;\ntext/markdown=mdview.desktop;\ntext/x-markdown=mdview.desktop;\n\n" +
+		"[Added Associations]\ntext/markdown=mdview.desktop;gedit.desktop;\ntext/x-markdown=mdview.desktop;\n"
+	if got := readFile(t, mimeAppsPath); got != want {
+		t.Errorf("unexpected mimeapps.list:\n%s\nwant:\n%s", got, want)
+	}
+
+	if err := Unregister(); err != nil {
+		t.Fatalf("Unregister failed: %v", err)
+	}
+
+	want = "[Default Applications]\ntext/html=firefox.desktop;\n\n" +
+		"[Added Associations]\ntext/markdown=gedit.desktop;\n"
+	if got := readFile(t, mimeAppsPath); got != want {
+		t.Errorf("unexpected mimeapps.list after Unregister:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestUnregister_RemovesDesktopEntry(t *testing.T) {
+	home := setupHome(t)
+
+	if err := Register(); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if err := Unregister(); err != nil {
+		t.Fatalf("Unregister failed: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(home, ".local/share/applications/mdview.desktop")); !os.IsNotExist(err) {
+		t.Error("expected desktop entry to be removed")
+	}
+	if got := readFile(t, filepath.Join(home, ".config/mimeapps.list")); strings.Contains(got, "mdview") {
+		t.Errorf("expected no mdview associations, got:\n%s", got)
+	}
+}
+
+func TestUnregister_NotRegistered(t *testing.T) {
+	home := setupHome(t)
+
+	if err := Unregister(); err != nil {
+		t.Fatalf("Unregister failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(home, ".config/mimeapps.list")); !os.IsNotExist(err) {
+		t.Error("expected Unregister not to create mimeapps.list")
+	}
+}
+
+func TestQuoteExec(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/usr/bin/mdview", `"/usr/bin/mdview"`},
+		{"/opt/my apps/mdview", `"/opt/my apps/mdview"`},
+		{`/odd/"$name"/100%`, `"/odd/\\"\\$name\\"/100%%"`},
+		{`/back\slash`, `"/back\\\\slash"`},
+	}
+
+	for _, tt := range tests {
+		if got := quoteExec(tt.path); got != tt.want {
+			t.Errorf("quoteExec(%q) = %s, want %s", tt.path, got, tt.want)
+		}
+	}
+}
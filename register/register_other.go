@@ -0,0 +1,20 @@
+//go:build !windows && !linux
+
+package register
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// Register sets mdview as the default program for .md files.
+// Not supported on this platform.
+func Register() error {
+	return fmt.Errorf("registering file associations is not supported on %s", runtime.GOOS)
+}
+
+// Unregister removes mdview as the default program for .md files.
+// Not supported on this platform.
+func Unregister() error {
+	return fmt.Errorf("registering file associations is not supported on %s", runtime.GOOS)
+}
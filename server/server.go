@@ -0,0 +1,248 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	htmlpkg "html"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"mdview/converter"
+)
+
+// indexFiles are rendered in place of a directory listing when present, in order of preference
+var indexFiles = []string{"README.md", "readme.md", "index.md"}
+
+// Server renders markdown files under a root directory on request.
+// Markdown files are converted on every request, so edits show up on refresh;
+// every other file (images, fonts, ...) is served as-is.
+type Server struct {
+	rootDir      string
+	templateName string
+	tocSidebar   bool
+	tocDepth     int
+	highlight    bool
+	extensions   converter.Extensions
+}
+
+// New creates a Server for the markdown files under rootDir
+func New(rootDir, templateName string) (*Server, error) {
+	absRoot, err := filepath.Abs(rootDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve root directory: %w", err)
+	}
+
+	info, err := os.Stat(absRoot)
+	if err != nil {
+		return nil, fmt.Errorf("root directory does not exist: %s", rootDir)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("root is not a directory: %s", rootDir)
+	}
+
+	return &Server{
+		rootDir:      absRoot,
+		templateName: templateName,
+		extensions:   converter.DefaultExtensions(),
+	}, nil
+}
+
+// SetTOCSidebar enables the table of contents sidebar on served pages
+func (s *Server) SetTOCSidebar(enabled bool) {
+	s.tocSidebar = enabled
+}
+
+// SetTOCDepth sets the deepest heading level included in tables of contents
+func (s *Server) SetTOCDepth(depth int) {
+	s.tocDepth = depth
+}
+
+// SetHighlight enables conversion-time syntax highlighting on served pages
+func (s *Server) SetHighlight(enabled bool) {
+	s.highlight = enabled
+}
+
+// SetExtensions selects the markdown extensions used for served pages
+func (s *Server) SetExtensions(ext converter.Extensions) {
+	s.extensions = ext
+}
+
+// RootDir returns the absolute path of the served directory
+func (s *Server) RootDir() string {
+	return s.rootDir
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// path.Clean on a rooted path removes any ".." so the result stays under rootDir
+	urlPath := path.Clean("/" + r.URL.Path)
+
+	// Hidden files and directories (.git, .env, ...) are left out of listings and never
+	// served, as they may hold secrets and the server can be shared on the network
+	if hasHiddenSegment(urlPath) {
+		http.NotFound(w, r)
+		return
+	}
+	localPath := filepath.Join(s.rootDir, filepath.FromSlash(urlPath))
+
+	info, err := os.Stat(localPath)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	if info.IsDir() {
+		// Directory URLs need a trailing slash so relative links resolve inside them
+		if !strings.HasSuffix(r.URL.Path, "/") {
+			http.Redirect(w, r, r.URL.Path+"/", http.StatusMovedPermanently)
+			return
+		}
+		s.serveDirectory(w, r, localPath, urlPath)
+		return
+	}
+
+	if strings.EqualFold(filepath.Ext(localPath), ".md") {
+		s.serveMarkdown(w, localPath)
+		return
+	}
+
+	http.ServeFile(w, r, localPath)
+}
+
+// hasHiddenSegment reports whether a cleaned URL path has a segment starting with "."
+func hasHiddenSegment(urlPath string) bool {
+	for _, segment := range strings.Split(urlPath, "/") {
+		if strings.HasPrefix(segment, ".") {
+			return true
+		}
+	}
+	return false
+}
+
+// serveMarkdown converts a markdown file and writes the resulting page
+func (s *Server) serveMarkdown(w http.ResponseWriter, mdPath string) {
+	mdFile, err := os.Open(mdPath)
+	if err != nil {
+		http.Error(w, "failed to open file", http.StatusInternalServerError)
+		return
+	}
+	defer mdFile.Close()
+
+	var fileSize int64
+	if stat, err := mdFile.Stat(); err == nil {
+		fileSize = stat.Size()
+	}
+
+	conv := s.newConverter(filepath.Dir(mdPath), strings.TrimSuffix(filepath.Base(mdPath), filepath.Ext(mdPath)))
+	conv.SetSourcePath(mdPath)
+
+	// Render into a buffer so a conversion error can still produce a proper status code
+	var buf bytes.Buffer
+	if err := conv.ConvertWithSize(mdFile, &buf, s.templateName, fileSize); err != nil {
+		http.Error(w, fmt.Sprintf("conversion failed: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	writePage(w, buf.Bytes())
+}
+
+// serveDirectory renders the directory's README/index if it has one, otherwise a listing
+func (s *Server) serveDirectory(w http.ResponseWriter, r *http.Request, dir, urlPath string) {
+	for _, name := range indexFiles {
+		indexPath := filepath.Join(dir, name)
+		if info, err := os.Stat(indexPath); err == nil && !info.IsDir() {
+			s.serveMarkdown(w, indexPath)
+			return
+		}
+	}
+
+	listing, err := directoryListing(dir, urlPath)
+	if err != nil {
+		http.Error(w, "failed to read directory", http.StatusInternalServerError)
+		return
+	}
+
+	conv := s.newConverter(dir, "Index of "+urlPath)
+	var buf bytes.Buffer
+	if err := conv.Convert(strings.NewReader(listing), &buf, s.templateName); err != nil {
+		http.Error(w, fmt.Sprintf("conversion failed: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	writePage(w, buf.Bytes())
+}
+
+// newConverter creates a converter that rewrites local paths to server URLs
+func (s *Server) newConverter(baseDir, title string) *converter.Converter {
+	conv := converter.New()
+	conv.SetBaseDir(baseDir)
+	conv.SetServeRoot(s.rootDir)
+	conv.SetTitle(title)
+	conv.SetTOCSidebar(s.tocSidebar)
+	conv.SetTOCDepth(s.tocDepth)
+	conv.SetHighlight(s.highlight)
+	conv.SetExtensions(s.extensions)
+	return conv
+}
+
+// directoryListing builds a markdown page listing subdirectories and markdown files
+func directoryListing(dir, urlPath string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+
+	var dirs, files []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+		if entry.IsDir() {
+			dirs = append(dirs, name)
+		} else if strings.EqualFold(filepath.Ext(name), ".md") {
+			files = append(files, name)
+		}
+	}
+	sort.Strings(dirs)
+	sort.Strings(files)
+
+	var sb strings.Builder
+	sb.WriteString("# Index of " + htmlpkg.EscapeString(urlPath) + "\n\n")
+	sb.WriteString("<ul>\n")
+	if urlPath != "/" {
+		sb.WriteString("<li><a href=\"../\">../</a></li>\n")
+	}
+	for _, name := range dirs {
+		writeListingEntry(&sb, name+"/")
+	}
+	for _, name := range files {
+		writeListingEntry(&sb, name)
+	}
+	sb.WriteString("</ul>\n")
+
+	return sb.String(), nil
+}
+
+// writeListingEntry writes a single <li> link for a directory listing
+func writeListingEntry(sb *strings.Builder, name string) {
+	href := (&url.URL{Path: name}).EscapedPath()
+	sb.WriteString("<li><a href=\"" + htmlpkg.EscapeString(href) + "\">" + htmlpkg.EscapeString(name) + "</a></li>\n")
+}
+
+// writePage writes a rendered HTML page
+func writePage(w http.ResponseWriter, page []byte) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Write(page)
+}
@@ -0,0 +1,208 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// createTestFile writes a file under dir, creating parent directories as needed
+func createTestFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+	return path
+}
+
+// get performs a GET request against the server and returns the recorded response
+func get(t *testing.T, srv *Server, target string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	return rec
+}
+
+func newTestServer(t *testing.T, dir string) *Server {
+	t.Helper()
+	srv, err := New(dir, "default")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return srv
+}
+
+func TestNew_RejectsMissingDirectory(t *testing.T) {
+	if _, err := New(filepath.Join(t.TempDir(), "missing"), "default"); err == nil {
+		t.Error("expected error for missing root directory")
+	}
+}
+
+func TestNew_RejectsFile(t *testing.T) {
+	path := createTestFile(t, t.TempDir(), "doc.md", "# Doc")
+	if _, err := New(path, "default"); err == nil {
+		t.Error("expected error when root is a file")
+	}
+}
+
+func TestServeMarkdown_RendersPage(t *testing.T) {
+	dir := t.TempDir()
+	createTestFile(t, dir, "docs/guide.md", "# Guide\n\nSee [setup](setup.md#install) and [up](../README.md).\n\n![logo](img/logo.png)\n")
+
+	rec := get(t, newTestServer(t, dir), "/docs/guide.md")
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("expected text/html content type, got %q", ct)
+	}
+
+	body := rec.Body.String()
+	wants := []string{
+		`<h1 id="guide">Guide</h1>`,
+		`href="/docs/setup.md#install"`,
+		`href="/README.md"`,
+		`src="/docs/img/logo.png"`,
+	}
+	for _, want := range wants {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in response, got:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "file:///") {
+		t.Error("expected no file:// URLs in served page")
+	}
+	if strings.Contains(body, `href="/docs/setup.md#install" target="_blank"`) {
+		t.Error("expected server links to open in the same tab")
+	}
+}
+
+func TestServeStaticFile(t *testing.T) {
+	dir := t.TempDir()
+	createTestFile(t, dir, "img/logo.svg", "<svg></svg>")
+
+	rec := get(t, newTestServer(t, dir), "/img/logo.svg")
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if rec.Body.String() != "<svg></svg>" {
+		t.Errorf("expected raw file contents, got %q", rec.Body.String())
+	}
+}
+
+func TestServeDirectory_RendersReadme(t *testing.T) {
+	dir := t.TempDir()
+	createTestFile(t, dir, "README.md", "# Project Readme")
+
+	rec := get(t, newTestServer(t, dir), "/")
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "Project Readme") {
+		t.Error("expected README to be rendered for directory")
+	}
+}
+
+func TestServeDirectory_Listing(t *testing.T) {
+	dir := t.TempDir()
+	createTestFile(t, dir, "docs/b.md", "# B")
+	createTestFile(t, dir, "docs/a file.md", "# A")
+	createTestFile(t, dir, "docs/image.png", "png")
+	createTestFile(t, dir, "docs/sub/c.md", "# C")
+
+	rec := get(t, newTestServer(t, dir), "/docs/")
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+
+	body := rec.Body.String()
+	wants := []string{
+		`href="/docs/a%20file.md"`,
+		`href="/docs/b.md"`,
+		`href="/docs/sub"`,
+		`href="/"`,
+	}
+	for _, want := range wants {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in listing, got:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "image.png") {
+		t.Error("expected non-markdown files to be left out of the listing")
+	}
+}
+
+func TestServeDirectory_RedirectsWithoutTrailingSlash(t *testing.T) {
+	dir := t.TempDir()
+	createTestFile(t, dir, "docs/a.md", "# A")
+
+	rec := get(t, newTestServer(t, dir), "/docs")
+
+	if rec.Code != http.StatusMovedPermanently {
+		t.Fatalf("expected 301, got %d", rec.Code)
+	}
+	if loc := rec.Header().Get("Location"); loc != "/docs/" {
+		t.Errorf("expected redirect to /docs/, got %q", loc)
+	}
+}
+
+func TestServe_NotFound(t *testing.T) {
+	rec := get(t, newTestServer(t, t.TempDir()), "/missing.md")
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", rec.Code)
+	}
+}
+
+func TestServe_PathTraversal(t *testing.T) {
+	parent := t.TempDir()
+	root := filepath.Join(parent, "root")
+	createTestFile(t, parent, "secret.md", "# Secret")
+	createTestFile(t, root, "README.md", "# Root")
+
+	rec := get(t, newTestServer(t, root), "/../secret.md")
+
+	if strings.Contains(rec.Body.String(), "Secret") {
+		t.Error("expected paths outside the root to be unreachable")
+	}
+}
+
+func TestServe_HiddenFiles(t *testing.T) {
+	root := t.TempDir()
+	createTestFile(t, root, ".env", "TOKEN=secret")
+	createTestFile(t, root, ".git/config", "[remote] secret")
+	createTestFile(t, root, "docs/.notes.md", "# Secret")
+	createTestFile(t, root, "docs/README.md", "# Docs")
+
+	srv := newTestServer(t, root)
+	for _, target := range []string{"/.env", "/.git/config", "/.git/", "/docs/.notes.md", "/docs/x/../.notes.md"} {
+		rec := get(t, srv, target)
+		if rec.Code != http.StatusNotFound || strings.Contains(rec.Body.String(), "secret") || strings.Contains(rec.Body.String(), "Secret") {
+			t.Errorf("expected %s to be hidden, got %d: %s", target, rec.Code, rec.Body.String())
+		}
+	}
+
+	if rec := get(t, srv, "/docs/README.md"); rec.Code != http.StatusOK {
+		t.Errorf("expected visible files to be served, got %d", rec.Code)
+	}
+}
+
+func TestServe_MethodNotAllowed(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newTestServer(t, t.TempDir()).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", nil))
+
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", rec.Code)
+	}
+}
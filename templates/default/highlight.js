@@ -1,5 +1,4 @@
-// mdview default template script
-// highlight.js for syntax highlighting
+// mdview code highlighting (left out with --highlight)
 
 /*!
   Highlight.js v11.9.0 (git: f47103d4f1)
@@ -3,29 +3,141 @@ package templates
 import (
 	"embed"
 	"fmt"
+	"io/fs"
+	"os"
 	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 )
 
-//go:embed default/*
+//go:embed */*
 var templateFS embed.FS
 
+// sharedHighlight maps embedded templates without a highlight.js of their own to the
+// embedded template whose highlight.js they use, so the binary carries one copy
+var sharedHighlight = map[string]string{
+	"light":        "default",
+	"dark":         "default",
+	"print":        "default",
+	"paper":        "default",
+	"presentation": "default",
+}
+
+// sharedStyles is the set of embedded templates whose template.css is layered on base.css,
+// so it only holds the theme's colors and the rules that differ
+var sharedStyles = map[string]bool{
+	"default":      true,
+	"light":        true,
+	"dark":         true,
+	"print":        true,
+	"paper":        true,
+	"presentation": true,
+}
+
+// baseCSS is the stylesheet shared by the templates in sharedStyles
+//
+//go:embed base.css
+var baseCSS string
+
+// defaultLayout is the page layout of templates without a layout.html
+//
+//go:embed layout.html
+var defaultLayout string
+
 // Template holds the content of a template's files
 type Template struct {
-	HTML string
-	CSS  string
-	JS   string
+	HTML      string // Extra <head> markup (template.html)
+	CSS       string
+	JS        string
+	Highlight string // Code highlighter (highlight.js), left out when code is highlighted at conversion
+	Layout    string // html/template page layout (layout.html, or the built-in layout)
+}
+
+var (
+	dirsMu   sync.RWMutex
+	userDirs = DefaultDirs()
+)
+
+// DefaultDirs returns the directories searched for user templates when SetDirs
+// hasn't been called: mdview/templates in the user config directory, e.g.
+// ~/.config/mdview/templates on Linux or %AppData%\mdview\templates on Windows.
+func DefaultDirs() []string {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return nil
+	}
+	return []string{filepath.Join(configDir, "mdview", "templates")}
+}
+
+// SetDirs sets the directories searched for user templates, in order of precedence.
+// Each template is a subdirectory with the same layout as the embedded ones
+// (template.html, template.css, template.js, highlight.js). User templates take precedence over
+// embedded templates with the same name.
+func SetDirs(dirs ...string) {
+	dirsMu.Lock()
+	defer dirsMu.Unlock()
+	userDirs = append([]string(nil), dirs...)
+}
+
+// Dirs returns the directories searched for user templates, in order of precedence
+func Dirs() []string {
+	dirsMu.RLock()
+	defer dirsMu.RUnlock()
+	return append([]string(nil), userDirs...)
+}
+
+// sources returns the file systems templates are loaded from, in order of precedence
+func sources() []fs.FS {
+	var result []fs.FS
+	for _, dir := range Dirs() {
+		result = append(result, os.DirFS(dir))
+	}
+	return append(result, templateFS)
 }
 
 // Get retrieves a template by name. Returns the template content or an error.
-// Missing files within a template are allowed (they'll be empty strings).
+// The user template directories are searched first, then the embedded templates.
+// Missing files within a template are allowed (they'll be empty strings), except
+// layout.html, which falls back to the built-in layout.
 func Get(name string) (*Template, error) {
-	t := &Template{}
+	if name == "" || name == "." || strings.ContainsAny(name, `/\`) || !fs.ValidPath(name) {
+		return nil, fmt.Errorf("template %q not found: invalid template name", name)
+	}
 
-	// Check if template directory exists by trying to read it
-	entries, err := templateFS.ReadDir(name)
-	if err != nil {
-		return nil, fmt.Errorf("template %q not found: %w", name, err)
+	var lastErr error
+	for _, source := range sources() {
+		// Check if template directory exists by trying to read it
+		entries, err := fs.ReadDir(source, name)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		t, err := load(source, name, entries)
+		if err != nil {
+			return nil, err
+		}
+		if source == fs.FS(templateFS) {
+			if shared, ok := sharedHighlight[name]; ok && t.Highlight == "" {
+				data, err := templateFS.ReadFile(path.Join(shared, "highlight.js"))
+				if err != nil {
+					return nil, fmt.Errorf("failed to read highlight.js: %w", err)
+				}
+				t.Highlight = string(data)
+			}
+			if sharedStyles[name] {
+				t.CSS = baseCSS + "\n" + t.CSS
+			}
+		}
+		return t, nil
 	}
+	return nil, fmt.Errorf("template %q not found: %w", name, lastErr)
+}
+
+// load reads the files of the template directory name in source
+func load(source fs.FS, name string, entries []fs.DirEntry) (*Template, error) {
+	t := &Template{}
 
 	// Build a set of available files
 	files := make(map[string]bool)
@@ -35,7 +147,7 @@ func Get(name string) (*Template, error) {
 
 	// Read template.html if it exists
 	if files["template.html"] {
-		data, err := templateFS.ReadFile(path.Join(name, "template.html"))
+		data, err := fs.ReadFile(source, path.Join(name, "template.html"))
 		if err != nil {
 			return nil, fmt.Errorf("failed to read template.html: %w", err)
 		}
@@ -44,7 +156,7 @@ func Get(name string) (*Template, error) {
 
 	// Read template.css if it exists
 	if files["template.css"] {
-		data, err := templateFS.ReadFile(path.Join(name, "template.css"))
+		data, err := fs.ReadFile(source, path.Join(name, "template.css"))
 		if err != nil {
 			return nil, fmt.Errorf("failed to read template.css: %w", err)
 		}
@@ -53,28 +165,60 @@ func Get(name string) (*Template, error) {
 
 	// Read template.js if it exists
 	if files["template.js"] {
-		data, err := templateFS.ReadFile(path.Join(name, "template.js"))
+		data, err := fs.ReadFile(source, path.Join(name, "template.js"))
 		if err != nil {
 			return nil, fmt.Errorf("failed to read template.js: %w", err)
 		}
 		t.JS = string(data)
 	}
 
+	// Read highlight.js if it exists
+	if files["highlight.js"] {
+		data, err := fs.ReadFile(source, path.Join(name, "highlight.js"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read highlight.js: %w", err)
+		}
+		t.Highlight = string(data)
+	}
+
+	// Read layout.html if it exists
+	t.Layout = defaultLayout
+	if files["layout.html"] {
+		data, err := fs.ReadFile(source, path.Join(name, "layout.html"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read layout.html: %w", err)
+		}
+		t.Layout = string(data)
+	}
+
 	return t, nil
 }
 
-// List returns the names of all available templates
+// List returns the names of all available templates, user and embedded, sorted.
+// User template directories that don't exist are skipped.
 func List() ([]string, error) {
-	entries, err := templateFS.ReadDir(".")
-	if err != nil {
-		return nil, err
+	all := sources()
+	seen := make(map[string]bool)
+	for i, source := range all {
+		entries, err := fs.ReadDir(source, ".")
+		if err != nil {
+			// Only the embedded templates (the last source) must be readable
+			if i == len(all)-1 {
+				return nil, err
+			}
+			continue
+		}
+		for _, e := range entries {
+			if e.IsDir() {
+				seen[e.Name()] = true
+			}
+		}
 	}
 
-	var names []string
-	for _, e := range entries {
-		if e.IsDir() {
-			names = append(names, e.Name())
-		}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
 	}
+	sort.Strings(names)
 	return names, nil
 }
@@ -1,6 +1,9 @@
 package templates
 
 import (
+	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 	"testing"
 )
@@ -16,9 +19,9 @@ func TestGetDefaultTemplate(t *testing.T) {
 		t.Error("expected default template to have CSS")
 	}
 
-	// Default template should have JS (highlight.js)
-	if tmpl.JS == "" {
-		t.Error("expected default template to have JS")
+	// Default template should have highlight.js
+	if tmpl.Highlight == "" {
+		t.Error("expected default template to have highlight.js")
 	}
 
 	// CSS should contain expected styling
@@ -26,9 +29,8 @@ func TestGetDefaultTemplate(t *testing.T) {
 		t.Error("expected CSS to contain markdown-body class")
 	}
 
-	// JS should contain highlight.js
-	if !strings.Contains(tmpl.JS, "hljs") {
-		t.Error("expected JS to contain highlight.js (hljs)")
+	if !strings.Contains(tmpl.Highlight, "hljs.highlightAll") {
+		t.Error("expected highlight.js to contain hljs.highlightAll")
 	}
 }
 
@@ -85,3 +87,239 @@ func BenchmarkGetTemplate(b *testing.B) {
 		}
 	}
 }
+
+// writeTemplate creates a user template directory with the given files
+func writeTemplate(t *testing.T, dir, name string, files map[string]string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Join(dir, name), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for file, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name, file), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// useDirs sets the user template directories for the duration of a test
+func useDirs(t *testing.T, dirs ...string) {
+	t.Helper()
+	previous := Dirs()
+	SetDirs(dirs...)
+	t.Cleanup(func() { SetDirs(previous...) })
+}
+
+func TestGetUserTemplate(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, dir, "brand", map[string]string{
+		"template.css":  ".brand { color: red; }",
+		"template.html": "<!-- header -->",
+	})
+	useDirs(t, dir)
+
+	tmpl, err := Get("brand")
+	if err != nil {
+		t.Fatalf("failed to get user template: %v", err)
+	}
+	if tmpl.CSS != ".brand { color: red; }" || tmpl.HTML != "<!-- header -->" {
+		t.Errorf("unexpected template content: %+v", tmpl)
+	}
+	if tmpl.JS != "" || tmpl.Highlight != "" {
+		t.Error("expected missing template.js and highlight.js to be empty")
+	}
+}
+
+func TestGetUserTemplatePrecedence(t *testing.T) {
+	first, second := t.TempDir(), t.TempDir()
+	writeTemplate(t, first, "brand", map[string]string{"template.css": "first"})
+	writeTemplate(t, second, "brand", map[string]string{"template.css": "second"})
+	writeTemplate(t, second, "default", map[string]string{"template.css": "override"})
+	useDirs(t, first, second)
+
+	tmpl, err := Get("brand")
+	if err != nil {
+		t.Fatalf("failed to get user template: %v", err)
+	}
+	if tmpl.CSS != "first" {
+		t.Errorf("expected the first directory to win, got CSS %q", tmpl.CSS)
+	}
+
+	tmpl, err = Get("default")
+	if err != nil {
+		t.Fatalf("failed to get default template: %v", err)
+	}
+	if tmpl.CSS != "override" {
+		t.Error("expected a user template to override the embedded one")
+	}
+}
+
+func TestGetFallsBackToEmbedded(t *testing.T) {
+	useDirs(t, filepath.Join(t.TempDir(), "missing"))
+
+	tmpl, err := Get("default")
+	if err != nil {
+		t.Fatalf("failed to get default template: %v", err)
+	}
+	if !strings.Contains(tmpl.CSS, "markdown-body") {
+		t.Error("expected the embedded default template")
+	}
+}
+
+func TestGetInvalidTemplateName(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, dir, "brand", map[string]string{"template.css": "x"})
+	useDirs(t, filepath.Join(dir, "sub"))
+
+	for _, name := range []string{"", ".", "..", "../brand", "a/b", `a\b`} {
+		if _, err := Get(name); err == nil {
+			t.Errorf("expected error for template name %q", name)
+		}
+	}
+}
+
+func TestListMergesUserTemplates(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, dir, "brand", map[string]string{"template.css": "x"})
+	writeTemplate(t, dir, "default", map[string]string{"template.css": "x"})
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a template"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	useDirs(t, dir, filepath.Join(dir, "missing"))
+
+	names, err := List()
+	if err != nil {
+		t.Fatalf("failed to list templates: %v", err)
+	}
+	counts := make(map[string]int)
+	for _, name := range names {
+		counts[name]++
+	}
+	if counts["brand"] != 1 || counts["default"] != 1 || counts["notes.txt"] != 0 {
+		t.Errorf("expected brand and default once each, got: %v", names)
+	}
+	if !sort.StringsAreSorted(names) {
+		t.Errorf("expected sorted names, got: %v", names)
+	}
+}
+
+func TestGetLayout(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, dir, "plain", map[string]string{"template.css": "x"})
+	writeTemplate(t, dir, "framed", map[string]string{"layout.html": "<main>{{.Content}}</main>"})
+	useDirs(t, dir)
+
+	tmpl, err := Get("plain")
+	if err != nil {
+		t.Fatalf("failed to get template: %v", err)
+	}
+	if tmpl.Layout != defaultLayout || !strings.Contains(tmpl.Layout, "{{.Content}}") {
+		t.Error("expected templates without layout.html to use the built-in layout")
+	}
+
+	tmpl, err = Get("framed")
+	if err != nil {
+		t.Fatalf("failed to get template: %v", err)
+	}
+	if tmpl.Layout != "<main>{{.Content}}</main>" {
+		t.Errorf("expected layout.html to be used, got %q", tmpl.Layout)
+	}
+}
+
+func TestBuiltinThemes(t *testing.T) {
+	useDirs(t)
+	defaultTmpl, err := Get("default")
+	if err != nil {
+		t.Fatalf("failed to get default template: %v", err)
+	}
+
+	names, err := List()
+	if err != nil {
+		t.Fatalf("failed to list templates: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		css     string // Expected in the CSS
+		shared  bool   // Shares base.css and the default template's highlight.js
+		noMedia bool   // Colors must not depend on prefers-color-scheme
+	}{
+		{"light", "color-scheme: light", true, true},
+		{"dark", "color-scheme: dark", true, true},
+		{"print", "break-inside: avoid", true, true},
+		{"paper", "serif", true, true},
+		{"presentation", "scroll-snap-type", true, true},
+		{"minimal", "markdown-alert", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !strings.Contains(strings.Join(names, ","), tt.name) {
+				t.Errorf("expected %s in template list %v", tt.name, names)
+			}
+
+			tmpl, err := Get(tt.name)
+			if err != nil {
+				t.Fatalf("failed to get template: %v", err)
+			}
+			if !strings.Contains(tmpl.CSS, "markdown-body") || !strings.Contains(tmpl.CSS, tt.css) {
+				t.Errorf("expected CSS with markdown-body and %q", tt.css)
+			}
+			if tt.noMedia && strings.Contains(tmpl.CSS, "prefers-color-scheme") {
+				t.Error("expected colors not to follow prefers-color-scheme")
+			}
+			if tt.shared && tmpl.Highlight != defaultTmpl.Highlight {
+				t.Error("expected the default template's highlight.js")
+			}
+			if !tt.shared && tmpl.Highlight != "" {
+				t.Error("expected no highlight.js")
+			}
+			if tt.shared != strings.HasPrefix(tmpl.CSS, baseCSS) {
+				t.Errorf("expected base.css layered under the CSS: %t", tt.shared)
+			}
+			if tmpl.JS != "" {
+				t.Error("expected no template.js")
+			}
+			if tmpl.Layout != defaultLayout {
+				t.Error("expected the built-in layout")
+			}
+		})
+	}
+}
+
+func TestSharedScriptsOnlyForEmbedded(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, dir, "light", map[string]string{"template.css": "x"})
+	useDirs(t, dir)
+
+	tmpl, err := Get("light")
+	if err != nil {
+		t.Fatalf("failed to get template: %v", err)
+	}
+	if tmpl.Highlight != "" {
+		t.Error("expected a user template named light not to get highlight.js")
+	}
+	if tmpl.CSS != "x" {
+		t.Errorf("expected a user template named light not to get base.css, got %q", tmpl.CSS)
+	}
+}
+
+func TestDefaultTemplateThemeToggle(t *testing.T) {
+	useDirs(t)
+	tmpl, err := Get("default")
+	if err != nil {
+		t.Fatalf("failed to get default template: %v", err)
+	}
+
+	// The toggle lives in template.html so it is kept in documents that don't use highlight.js
+	if !strings.Contains(tmpl.HTML, "theme-toggle") || !strings.Contains(tmpl.HTML, "localStorage") {
+		t.Error("expected template.html to add the theme toggle")
+	}
+	if !strings.Contains(tmpl.HTML, "<title>Markdown Preview</title>") {
+		t.Error("expected template.html to set the default title")
+	}
+	for _, want := range []string{`:root[data-theme="light"]`, `:root:not([data-theme="dark"])`, ".theme-toggle"} {
+		if !strings.Contains(tmpl.CSS, want) {
+			t.Errorf("expected %q in CSS", want)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package watch
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultInterval is how often watched files are polled for changes
+const DefaultInterval = 500 * time.Millisecond
+
+// fileState is the snapshot of a file used to detect modifications
+type fileState struct {
+	modTime time.Time
+	size    int64
+	exists  bool
+}
+
+// Watcher polls a set of files and reports when any of them change.
+// Polling is used instead of OS notifications so behaviour is identical on every
+// platform and editors that save via rename/replace are still detected.
+type Watcher struct {
+	interval time.Duration
+	mu       sync.Mutex
+	files    map[string]fileState // path -> last observed state
+}
+
+// New creates a Watcher that polls at the given interval.
+// If interval is 0 or negative, DefaultInterval is used.
+func New(interval time.Duration) *Watcher {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	return &Watcher{
+		interval: interval,
+		files:    make(map[string]fileState),
+	}
+}
+
+// SetPaths replaces the set of watched files and records their current state.
+// Changes made before SetPaths is called are not reported.
+func (w *Watcher) SetPaths(paths []string) {
+	files := make(map[string]fileState, len(paths))
+	for _, path := range paths {
+		files[path] = statFile(path)
+	}
+
+	w.mu.Lock()
+	w.files = files
+	w.mu.Unlock()
+}
+
+// SetPathsSince replaces the set of watched files like SetPaths, but files modified at
+// or after since are reported by the next Changed. Pass the time the caller started
+// reading the files (e.g. the start of a rebuild), so a save that lands while they are
+// being read still triggers another rebuild instead of becoming part of the snapshot.
+func (w *Watcher) SetPathsSince(paths []string, since time.Time) {
+	files := make(map[string]fileState, len(paths))
+	for _, path := range paths {
+		state := statFile(path)
+		if state.exists && !state.modTime.Before(since) {
+			// A state no file can have, so the file counts as changed
+			state = fileState{size: -1, exists: true}
+		}
+		files[path] = state
+	}
+
+	w.mu.Lock()
+	w.files = files
+	w.mu.Unlock()
+}
+
+// Paths returns the currently watched files in sorted order
+func (w *Watcher) Paths() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	paths := make([]string, 0, len(w.files))
+	for path := range w.files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Changed returns the watched files whose state differs from the last snapshot
+// and updates the snapshot. Files that are created or deleted count as changed.
+func (w *Watcher) Changed() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var changed []string
+	for path, old := range w.files {
+		current := statFile(path)
+		if current != old {
+			w.files[path] = current
+			changed = append(changed, path)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// Wait blocks until at least one watched file changes or stop is closed.
+// After the first change is seen it keeps polling until the files settle, so an
+// editor writing a file in several steps triggers a single rebuild.
+// Returns nil if stop was closed.
+func (w *Watcher) Wait(stop <-chan struct{}) []string {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	var changed []string
+	for {
+		select {
+		case <-stop:
+			return nil
+		case <-ticker.C:
+		}
+
+		more := w.Changed()
+		if len(more) == 0 && len(changed) > 0 {
+			return changed
+		}
+		changed = mergePaths(changed, more)
+	}
+}
+
+// statFile returns the current state of a file (zero state if it doesn't exist)
+func statFile(path string) fileState {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileState{}
+	}
+	return fileState{
+		modTime: info.ModTime(),
+		size:    info.Size(),
+		exists:  true,
+	}
+}
+
+// mergePaths appends paths from b that aren't already in a
+func mergePaths(a, b []string) []string {
+	seen := make(map[string]bool, len(a))
+	for _, path := range a {
+		seen[path] = true
+	}
+	for _, path := range b {
+		if !seen[path] {
+			seen[path] = true
+			a = append(a, path)
+		}
+	}
+	return a
+}
+
+// ReloadScriptPath returns the path of the live reload sidecar script for an output file.
+// The sidecar lives next to the HTML so the page can load it with a relative URL.
+func ReloadScriptPath(outputPath string) string {
+	return outputPath + ".reload.js"
+}
+
+// NewReloadToken returns a token that identifies one generation of the output
+func NewReloadToken() string {
+	return fmt.Sprintf("%x", time.Now().UnixNano())
+}
+
+// WriteReloadScript writes the live reload sidecar for outputPath.
+// Pages generated with the same token ignore it; older pages reload themselves.
+func WriteReloadScript(outputPath, token string) error {
+	script := fmt.Sprintf("window.mdviewLiveReloadCheck && window.mdviewLiveReloadCheck(%q);\n", token)
+	if err := os.WriteFile(ReloadScriptPath(outputPath), []byte(script), 0644); err != nil {
+		return fmt.Errorf("failed to write reload script: %w", err)
+	}
+	return nil
+}
+
+// RemoveReloadScript deletes the live reload sidecar for outputPath, ignoring missing files
+func RemoveReloadScript(outputPath string) error {
+	err := os.Remove(ReloadScriptPath(outputPath))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package watch
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// touch writes content to a file and bumps its modification time so that
+// changes are visible even on filesystems with coarse timestamps
+func touch(t *testing.T, path, content string, offset time.Duration) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+	modTime := time.Now().Add(offset)
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("failed to set mod time on %s: %v", path, err)
+	}
+}
+
+func TestChanged_NoChanges(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "doc.md")
+	touch(t, path, "# Doc", 0)
+
+	w := New(10 * time.Millisecond)
+	w.SetPaths([]string{path})
+
+	if changed := w.Changed(); len(changed) != 0 {
+		t.Errorf("expected no changes, got %v", changed)
+	}
+}
+
+func TestChanged_DetectsModification(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "doc.md")
+	touch(t, path, "# Doc", -time.Hour)
+
+	w := New(10 * time.Millisecond)
+	w.SetPaths([]string{path})
+
+	touch(t, path, "# Doc edited", 0)
+
+	changed := w.Changed()
+	if len(changed) != 1 || changed[0] != path {
+		t.Fatalf("expected [%s], got %v", path, changed)
+	}
+
+	// Snapshot is updated, so the same change is not reported twice
+	if changed := w.Changed(); len(changed) != 0 {
+		t.Errorf("expected change to be reported once, got %v", changed)
+	}
+}
+
+func TestChanged_DetectsCreateAndDelete(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "existing.png")
+	missing := filepath.Join(dir, "missing.png")
+	touch(t, existing, "png", 0)
+
+	w := New(10 * time.Millisecond)
+	w.SetPaths([]string{existing, missing})
+
+	if err := os.Remove(existing); err != nil {
+		t.Fatalf("failed to remove file: %v", err)
+	}
+	touch(t, missing, "png", 0)
+
+	changed := w.Changed()
+	if len(changed) != 2 {
+		t.Errorf("expected both files to be reported, got %v", changed)
+	}
+}
+
+func TestSetPaths_ReplacesWatchList(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.md")
+	b := filepath.Join(dir, "b.md")
+	touch(t, a, "a", -time.Hour)
+	touch(t, b, "b", -time.Hour)
+
+	w := New(10 * time.Millisecond)
+	w.SetPaths([]string{a})
+	w.SetPaths([]string{b})
+
+	paths := w.Paths()
+	if len(paths) != 1 || paths[0] != b {
+		t.Fatalf("expected only %s to be watched, got %v", b, paths)
+	}
+
+	// Changes to files no longer watched are ignored
+	touch(t, a, "a edited", 0)
+	if changed := w.Changed(); len(changed) != 0 {
+		t.Errorf("expected unwatched file to be ignored, got %v", changed)
+	}
+}
+
+func TestSetPathsSince_ReportsFilesSavedDuringBuild(t *testing.T) {
+	dir := t.TempDir()
+	before := filepath.Join(dir, "before.md")
+	during := filepath.Join(dir, "during.md")
+	touch(t, before, "before", -time.Hour)
+
+	buildStart := time.Now().Add(-time.Minute)
+	// Saved after the build started reading the files
+	touch(t, during, "during", 0)
+
+	w := New(10 * time.Millisecond)
+	w.SetPathsSince([]string{before, during}, buildStart)
+
+	changed := w.Changed()
+	if len(changed) != 1 || changed[0] != during {
+		t.Fatalf("expected [%s], got %v", during, changed)
+	}
+	if changed := w.Changed(); len(changed) != 0 {
+		t.Errorf("expected the change to be reported once, got %v", changed)
+	}
+}
+
+func TestWait_ReturnsOnChange(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "doc.md")
+	touch(t, path, "# Doc", -time.Hour)
+
+	w := New(10 * time.Millisecond)
+	w.SetPaths([]string{path})
+
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		os.WriteFile(path, []byte("# Doc edited"), 0644)
+	}()
+
+	stop := make(chan struct{})
+	timer := time.AfterFunc(5*time.Second, func() { close(stop) })
+	defer timer.Stop()
+
+	changed := w.Wait(stop)
+	if len(changed) != 1 || changed[0] != path {
+		t.Errorf("expected [%s], got %v", path, changed)
+	}
+}
+
+func TestWait_ReturnsNilWhenStopped(t *testing.T) {
+	w := New(10 * time.Millisecond)
+	w.SetPaths(nil)
+
+	stop := make(chan struct{})
+	close(stop)
+
+	if changed := w.Wait(stop); changed != nil {
+		t.Errorf("expected nil after stop, got %v", changed)
+	}
+}
+
+func TestWriteReloadScript(t *testing.T) {
+	dir := t.TempDir()
+	outputPath := filepath.Join(dir, "out.html")
+
+	if err := WriteReloadScript(outputPath, "abc123"); err != nil {
+		t.Fatalf("WriteReloadScript() error = %v", err)
+	}
+
+	data, err := os.ReadFile(ReloadScriptPath(outputPath))
+	if err != nil {
+		t.Fatalf("failed to read reload script: %v", err)
+	}
+	if !strings.Contains(string(data), `mdviewLiveReloadCheck("abc123")`) {
+		t.Errorf("reload script missing token call, got: %s", data)
+	}
+
+	if err := RemoveReloadScript(outputPath); err != nil {
+		t.Fatalf("RemoveReloadScript() error = %v", err)
+	}
+	if _, err := os.Stat(ReloadScriptPath(outputPath)); !os.IsNotExist(err) {
+		t.Error("expected reload script to be removed")
+	}
+
+	// Removing again is not an error
+	if err := RemoveReloadScript(outputPath); err != nil {
+		t.Errorf("RemoveReloadScript() on missing file error = %v", err)
+	}
+}